@@ -0,0 +1,41 @@
+package httplab
+
+import (
+	"context"
+	"net"
+)
+
+type brokersKey struct{}
+
+// Brokers are the SSE and WebSocket brokers of a server, Response.Serve serves the SSE bodies
+// and the WebSocket handshakes of its requests through them.
+type Brokers struct {
+	Events  *EventBroker
+	Sockets *SocketBroker
+}
+
+// NewBrokers returns new brokers, without any client.
+func NewBrokers() *Brokers {
+	return &Brokers{Events: &EventBroker{}, Sockets: &SocketBroker{}}
+}
+
+// BaseContext attaches b to the requests of a server, it's meant to be set as its http.Server.BaseContext.
+func (b *Brokers) BaseContext(net.Listener) context.Context {
+	return context.WithValue(context.Background(), brokersKey{}, b)
+}
+
+// Close ends the SSE streams and the WebSocket connections, servers should call it on shutdown,
+// as http.Server.Shutdown waits for the streams and doesn't close the hijacked connections.
+func (b *Brokers) Close() {
+	b.Events.Close()
+	b.Sockets.Close()
+}
+
+// brokersFromContext returns the brokers of the server ctx comes from. Without them, brokers of its own
+// are returned, which serve the response but nothing can be broadcast through.
+func brokersFromContext(ctx context.Context) *Brokers {
+	if b, ok := ctx.Value(brokersKey{}).(*Brokers); ok {
+		return b
+	}
+	return NewBrokers()
+}
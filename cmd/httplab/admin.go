@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gchaincl/httplab"
+)
+
+// Admin is a Lab that can be managed at runtime through the admin API.
+type Admin interface {
+	Lab
+	Response() *httplab.Response
+	SetResponse(resp *httplab.Response)
+	Responses() map[string]*httplab.Response
+	SaveResponse(name string, resp *httplab.Response) error
+	DeleteResponse(name string) (bool, error)
+	Requests() []*httplab.Request
+	ResetRequests() error
+}
+
+// NewAdminHandler returns the http.Handler serving the admin API:
+//
+//	GET    /response          the active response
+//	PUT    /response          replaces the active response
+//	GET    /responses         the saved responses
+//	PUT    /responses/{name}  saves a response
+//	DELETE /responses/{name}  deletes a saved response
+//	GET    /requests          the captured requests
+//	DELETE /requests          clears the request history
+func NewAdminHandler(admin Admin) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/response", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, admin.Response())
+		case http.MethodPut, http.MethodPost:
+			resp, err := decodeResponse(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			admin.SetResponse(resp)
+			admin.Info("Response updated via admin API")
+			writeJSON(w, http.StatusOK, resp)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, nil)
+		}
+	})
+
+	mux.HandleFunc("/responses", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, admin.Responses())
+	})
+
+	mux.HandleFunc("/responses/", func(w http.ResponseWriter, req *http.Request) {
+		name := strings.TrimPrefix(req.URL.Path, "/responses/")
+		if name == "" || strings.Contains(name, "/") {
+			writeError(w, http.StatusNotFound, nil)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			resp, ok := admin.Responses()[name]
+			if !ok {
+				writeError(w, http.StatusNotFound, nil)
+				return
+			}
+			writeJSON(w, http.StatusOK, resp)
+		case http.MethodPut, http.MethodPost:
+			resp, err := decodeResponse(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if err := admin.SaveResponse(name, resp); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			writeJSON(w, http.StatusCreated, resp)
+		case http.MethodDelete:
+			ok, err := admin.DeleteResponse(name)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if !ok {
+				writeError(w, http.StatusNotFound, nil)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, nil)
+		}
+	})
+
+	mux.HandleFunc("/requests", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			reqs := admin.Requests()
+			if reqs == nil {
+				reqs = []*httplab.Request{}
+			}
+			writeJSON(w, http.StatusOK, reqs)
+		case http.MethodDelete:
+			if err := admin.ResetRequests(); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, nil)
+		}
+	})
+
+	return mux
+}
+
+// newAdminServer returns the admin API server, it's bound to host since the API isn't authenticated.
+func newAdminServer(host string, port int, admin Admin) *http.Server {
+	return &http.Server{
+		Addr:    net.JoinHostPort(host, strconv.Itoa(port)),
+		Handler: NewAdminHandler(admin),
+	}
+}
+
+func decodeResponse(req *http.Request) (*httplab.Response, error) {
+	resp := &httplab.Response{}
+	if err := json.NewDecoder(req.Body).Decode(resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	if resp.Status < 100 || resp.Status > 599 {
+		return nil, fmt.Errorf("Status should be between 100 and 599")
+	}
+	return resp, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	msg := http.StatusText(status)
+	if err != nil {
+		msg = err.Error()
+	}
+	writeJSON(w, status, struct{ Error string }{msg})
+}
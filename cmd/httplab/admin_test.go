@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gchaincl/httplab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConsole(t *testing.T) *console {
+	resp, err := httplab.NewResponse("200", "", "Hello")
+	require.NoError(t, err)
+
+	lab, err := newConsole(os.Stdout, resp, []string{filepath.Join(t.TempDir(), "httplab.json")}, nil)
+	require.NoError(t, err)
+	lab.out = io.Discard
+	return lab
+}
+
+func doAdmin(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminResponse(t *testing.T) {
+	lab := newTestConsole(t)
+	admin := NewAdminHandler(lab)
+	srv := httptest.NewServer(NewHandler(lab, nil))
+	defer srv.Close()
+
+	rec := doAdmin(t, admin, "PUT", "/response", `{"Status": 500, "Body": "boom"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, 500, resp.StatusCode)
+	assert.Equal(t, "boom", string(body))
+
+	rec = doAdmin(t, admin, "GET", "/response", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var active httplab.Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &active))
+	assert.Equal(t, 500, active.Status)
+
+	rec = doAdmin(t, admin, "PUT", "/response", `{"Status": 999}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doAdmin(t, admin, "PATCH", "/response", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestAdminResponses(t *testing.T) {
+	lab := newTestConsole(t)
+	admin := NewAdminHandler(lab)
+
+	rec := doAdmin(t, admin, "PUT", "/responses/created", `{"Status": 201}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = doAdmin(t, admin, "GET", "/responses", "")
+	var list map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Contains(t, list, "created")
+
+	// it has to be persisted
+	rl := httplab.NewResponsesList()
+	require.NoError(t, rl.Load(lab.configPath))
+	require.NotNil(t, rl.Get("created"))
+	assert.Equal(t, 201, rl.Get("created").Status)
+
+	rec = doAdmin(t, admin, "GET", "/responses/created", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doAdmin(t, admin, "DELETE", "/responses/created", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doAdmin(t, admin, "DELETE", "/responses/created", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAdminResponsesMergesConfigChanges(t *testing.T) {
+	lab := newTestConsole(t)
+	admin := NewAdminHandler(lab)
+
+	// saved by another instance, like a recording one
+	other := httplab.NewResponsesList()
+	require.NoError(t, other.Load(lab.configPath))
+	other.Add("external", &httplab.Response{Status: 202})
+	require.NoError(t, other.Save(lab.configPath))
+
+	rec := doAdmin(t, admin, "PUT", "/responses/created", `{"Status": 201}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rl := httplab.NewResponsesList()
+	require.NoError(t, rl.Load(lab.configPath))
+	assert.Equal(t, []string{"created", "external"}, rl.Keys())
+}
+
+func TestAdminRequests(t *testing.T) {
+	lab := newTestConsole(t)
+	admin := NewAdminHandler(lab)
+	srv := httptest.NewServer(NewHandler(lab, nil))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/foo", "text/plain", bytes.NewBufferString("payload"))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	rec := doAdmin(t, admin, "GET", "/requests", "")
+	var reqs []httplab.Request
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &reqs))
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "POST", reqs[0].Method)
+	assert.Equal(t, "/foo", reqs[0].URL)
+	assert.Equal(t, "payload", string(reqs[0].Body))
+
+	rec = doAdmin(t, admin, "DELETE", "/requests", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doAdmin(t, admin, "GET", "/requests", "")
+	assert.Equal(t, "[]\n", rec.Body.String())
+}
+
+func TestAdminServerAddr(t *testing.T) {
+	lab := newTestConsole(t)
+	assert.Equal(t, "127.0.0.1:10081", newAdminServer("127.0.0.1", 10081, lab).Addr)
+	assert.Equal(t, ":10081", newAdminServer("", 10081, lab).Addr)
+}
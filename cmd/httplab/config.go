@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gchaincl/httplab"
+	flag "github.com/spf13/pflag"
+)
+
+func runConfig(argv []string) error {
+	if len(argv) == 0 || argv[0] != "convert" {
+		fmt.Fprintf(os.Stderr, "Usage of %s config:\n  convert\t: Converts a config file between JSON, YAML and TOML\n", os.Args[0])
+		return fmt.Errorf("config: a command is required")
+	}
+	return runConfigConvert(argv[1:])
+}
+
+func runConfigConvert(argv []string) error {
+	var force bool
+
+	fs := flag.NewFlagSet("config convert", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s config convert [flags] <config file> <output file>:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "The formats are guessed from the extensions: .yaml or .yml, .toml, JSON otherwise.\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVarP(&force, "force", "f", false, "Overwrites the output file if it exists.")
+	if err := fs.Parse(argv); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("config convert: a config file and an output file are required")
+	}
+	input, output := fs.Arg(0), fs.Arg(1)
+
+	if _, err := os.Stat(output); err == nil && !force {
+		return fmt.Errorf("config convert: %s already exists, use --force to overwrite it", output)
+	}
+	return httplab.ConvertConfig(input, output)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/gchaincl/httplab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigConvertCommand(t *testing.T) {
+	dir := t.TempDir()
+	yml := filepath.Join(dir, "httplab.yaml")
+	toml := filepath.Join(dir, "httplab.toml")
+	json := filepath.Join(dir, "httplab.json")
+
+	require.NoError(t, runConfig([]string{"convert", "../../.httplab.sample", yml}))
+	require.NoError(t, runConfig([]string{"convert", yml, toml}))
+	require.NoError(t, runConfig([]string{"convert", toml, json}))
+
+	err := runConfig([]string{"convert", toml, json})
+	assert.Error(t, err, "the output exists")
+	require.NoError(t, runConfig([]string{"convert", "--force", toml, json}))
+
+	sample := httplab.NewResponsesList()
+	require.NoError(t, sample.Load("../../.httplab.sample"))
+	converted := httplab.NewResponsesList()
+	require.NoError(t, converted.Load(json))
+
+	assert.Equal(t, sample.Keys(), converted.Keys())
+	assert.Equal(t, sample.Get("create").Body.Payload(), converted.Get("create").Body.Payload())
+	assert.Equal(t, sample.Get("slow").Delay, converted.Get("slow").Delay)
+	assert.Len(t, converted.Routes, len(sample.Routes))
+
+	assert.Error(t, runConfig([]string{"convert", filepath.Join(dir, "missing.json"), filepath.Join(dir, "out.yaml")}))
+	assert.Error(t, runConfig([]string{"unknown"}))
+}
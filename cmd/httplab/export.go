@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gchaincl/httplab"
+	flag "github.com/spf13/pflag"
+)
+
+func runExport(argv []string) error {
+	var (
+		format  string
+		output  string
+		indexes []int
+	)
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s export [flags] <history file>:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.StringVarP(&format, "format", "f", "", "Export format: dump, curl, raw or har. Guessed from the output extension by default.")
+	fs.IntSliceVarP(&indexes, "index", "i", nil, "Exports only these requests, starting at 1. Negative values count from the last one.")
+	fs.StringVarP(&output, "output", "o", "", "Writes into this file instead of stdout.")
+	if err := fs.Parse(argv); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("export: a history file is required")
+	}
+
+	reqs, err := httplab.NewHistory(fs.Arg(0), 0, 0).Load()
+	if err != nil {
+		return err
+	}
+
+	reqs, err = selectRequests(reqs, indexes)
+	if err != nil {
+		return err
+	}
+
+	f := httplab.ExportFormatFor(output)
+	if format != "" {
+		if f, err = httplab.ParseExportFormat(format); err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.OpenFile(output, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return httplab.Export(w, f, reqs)
+}
+
+// selectRequests picks reqs by their 1-based indexes, negative indexes count from the end.
+func selectRequests(reqs []*httplab.Request, indexes []int) ([]*httplab.Request, error) {
+	if len(indexes) == 0 {
+		return reqs, nil
+	}
+
+	var selected []*httplab.Request
+	for _, i := range indexes {
+		n := i
+		if n < 0 {
+			n = len(reqs) + n + 1
+		}
+		if n < 1 || n > len(reqs) {
+			return nil, fmt.Errorf("request %d out of range, there are %d requests", i, len(reqs))
+		}
+		selected = append(selected, reqs[n-1])
+	}
+	return selected, nil
+}
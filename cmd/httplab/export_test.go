@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gchaincl/httplab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportCommand(t *testing.T) {
+	dir := t.TempDir()
+	lab := newTestConsole(t)
+	lab.history = httplab.NewHistory(filepath.Join(dir, "history"), 0, 0)
+
+	srv := httptest.NewServer(NewHandler(lab, nil))
+	defer srv.Close()
+	for _, path := range []string{"/first", "/second", "/third"} {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// requests are persisted as they arrive, their replies once they've been sent
+	eventually(t, func() bool {
+		reqs, err := lab.history.Load()
+		return err == nil && len(reqs) == 3 && reqs[2].Reply != nil
+	})
+
+	out := filepath.Join(dir, "requests.har")
+	require.NoError(t, runExport([]string{"-i", "1,-1", "-o", out, lab.history.Path}))
+
+	buf, err := os.ReadFile(out)
+	require.NoError(t, err)
+
+	var doc struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					URL string `json:"url"`
+				} `json:"request"`
+				Response struct {
+					Status  int `json:"status"`
+					Content struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	require.NoError(t, json.Unmarshal(buf, &doc))
+	require.Len(t, doc.Log.Entries, 2)
+	assert.Equal(t, srv.URL+"/first", doc.Log.Entries[0].Request.URL)
+	assert.Equal(t, srv.URL+"/third", doc.Log.Entries[1].Request.URL)
+	assert.Equal(t, 200, doc.Log.Entries[1].Response.Status)
+	assert.Equal(t, "Hello", doc.Log.Entries[1].Response.Content.Text)
+
+	assert.Error(t, runExport([]string{"-i", "4", lab.history.Path}))
+	assert.Error(t, runExport([]string{"-f", "xml", lab.history.Path}))
+	assert.Error(t, runExport(nil))
+}
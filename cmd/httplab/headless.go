@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gchaincl/httplab"
+)
+
+// console is the Lab used on headless mode, it prints every request to out.
+type console struct {
+	out       io.Writer
+	color     bool
+	resp      *httplab.Response
+	responses *httplab.ResponsesList
+	// configPaths are the config files loaded, configPath the last one, where new responses are saved.
+	configPaths []string
+	configPath  string
+	requests    []*httplab.Request
+	history     *httplab.History
+	truncate    bool
+	mu          sync.Mutex
+}
+
+func newConsole(out *os.File, resp *httplab.Response, configPaths []string, history *httplab.History) (*console, error) {
+	c := &console{
+		out:         out,
+		color:       isTerminal(out),
+		resp:        resp,
+		responses:   httplab.NewResponsesList(),
+		configPaths: configPaths,
+		configPath:  configPaths[len(configPaths)-1],
+		history:     history,
+	}
+
+	for _, path := range configPaths {
+		if _, err := os.Stat(path); err == nil {
+			if err := c.responses.Load(configPaths...); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if history != nil {
+		reqs, err := history.Load()
+		if err != nil {
+			return nil, err
+		}
+		c.requests = reqs
+	}
+
+	return c, nil
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+func (c *console) Match(req *http.Request) (*httplab.Route, *httplab.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if route, resp := c.responses.Match(req); route != nil {
+		return route, resp
+	}
+	return nil, c.resp
+}
+
+func (c *console) AddRequest(req *httplab.Request) error {
+	buf, err := req.Dump()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests = append(c.requests, req)
+	if !c.color {
+		buf = httplab.Decolorize(buf)
+	}
+
+	fmt.Fprintf(c.out, "--- %s from %s", req.ReceivedAt.Format(time.RFC3339), req.RemoteAddr)
+	if req.Route != "" {
+		fmt.Fprintf(c.out, " (Route: %s)", req.Route)
+	}
+	fmt.Fprintf(c.out, "\n%s\n\n", bytes.TrimRight(buf, "\n"))
+
+	if c.history != nil {
+		return c.history.Append(req)
+	}
+	return nil
+}
+
+func (c *console) SetReply(req *httplab.Request, reply *httplab.Reply) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req.Reply = reply
+	if req.Upstream != "" {
+		buf, err := reply.Dump()
+		if err != nil {
+			return err
+		}
+		if !c.color {
+			buf = httplab.Decolorize(buf)
+		}
+		fmt.Fprintf(c.out, "--- reply from %s\n%s\n\n", req.Upstream, bytes.TrimRight(buf, "\n"))
+	}
+
+	if c.history != nil {
+		return c.history.AppendReply(req)
+	}
+	return nil
+}
+
+func (c *console) Info(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+func (c *console) Response() *httplab.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resp
+}
+
+func (c *console) SetResponse(resp *httplab.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resp = resp
+}
+
+func (c *console) Responses() map[string]*httplab.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := make(map[string]*httplab.Response, len(c.responses.List))
+	for key, resp := range c.responses.List {
+		list[key] = resp
+	}
+	return list
+}
+
+func (c *console) SaveResponse(name string, resp *httplab.Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.responses.Del(name)
+	c.responses.Add(name, resp)
+	return c.saveResponses()
+}
+
+func (c *console) DeleteResponse(name string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.responses.Del(name) {
+		return false, nil
+	}
+	return true, c.saveResponses()
+}
+
+// watchConfig reloads the responses every interval when the config file changes, until ctx is done.
+// Parse errors are reported once, the last good config is kept meanwhile.
+func (c *console) watchConfig(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.Lock()
+		diff, err := c.responses.Reload(c.configPaths...)
+		c.mu.Unlock()
+
+		if err != nil {
+			if err.Error() != lastErr {
+				c.Info("Config not reloaded: %v", err)
+			}
+			lastErr = err.Error()
+			continue
+		}
+		lastErr = ""
+
+		if diff != nil {
+			c.Info("Config reloaded: %s", diff)
+		}
+	}
+}
+
+// saveResponses saves the responses, taking in the changes made to the config file meanwhile.
+func (c *console) saveResponses() error {
+	merged, err := c.responses.SaveMerging(c.configPath)
+	if len(merged) > 0 {
+		c.Info("Merged the changes made to the config: %s", strings.Join(merged, ", "))
+	}
+	return err
+}
+
+func (c *console) Requests() []*httplab.Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reqs := make([]*httplab.Request, len(c.requests))
+	for i, req := range c.requests {
+		r := *req
+		reqs[i] = &r
+	}
+	return reqs
+}
+
+func (c *console) ResetRequests() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = nil
+
+	if c.history != nil && c.truncate {
+		return c.history.Truncate()
+	}
+	return nil
+}
+
+func runHeadless(args cmdArgs, middleware func(next http.Handler) http.Handler) error {
+	resp, err := newResponse(&args)
+	if err != nil {
+		return err
+	}
+
+	lab, err := newConsole(os.Stdout, resp, args.configs, newHistory(&args))
+	if err != nil {
+		return err
+	}
+	lab.truncate = args.historyTrunc
+
+	proxy, err := newProxy(&args, lab)
+	if err != nil {
+		return err
+	}
+
+	handlerLab, err := newRecordingLab(&args, lab)
+	if err != nil {
+		return err
+	}
+
+	srv, err := newServer(&args, middleware(NewHandler(handlerLab, proxy)), httplab.NewBrokers())
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go lab.watchConfig(ctx, time.Second)
+
+	// the addresses are bound first, so that a port clash is reported before anything's logged
+	ln, err := listen(srv)
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening on :%d", listenPort(ln))
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	if args.adminPort != 0 {
+		admin := newAdminServer(args.adminHost, args.adminPort, lab)
+		defer admin.Close()
+		adminLn, err := net.Listen("tcp", admin.Addr)
+		if err != nil {
+			srv.Close()
+			return err
+		}
+		log.Printf("Admin API listening on %s", adminLn.Addr())
+		go func() {
+			errCh <- admin.Serve(adminLn)
+		}()
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("HTTPLab is shutting down")
+	shutdown(srv)
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsoleWatchConfig(t *testing.T) {
+	lab := newTestConsole(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go lab.watchConfig(ctx, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(lab.configPath, []byte(`{
+		"Responses": {"created": {"Status": 201}},
+		"Routes": [{"Path": "/new", "Response": "created"}]
+	}`), 0644))
+
+	eventually(t, func() bool {
+		route, _ := lab.Match(httptest.NewRequest("GET", "/new", nil))
+		return route != nil
+	})
+
+	// a broken config doesn't drop the routes
+	require.NoError(t, os.WriteFile(lab.configPath, []byte(`{"Routes": [`), 0644))
+	time.Sleep(50 * time.Millisecond)
+	route, resp := lab.Match(httptest.NewRequest("GET", "/new", nil))
+	require.NotNil(t, route)
+	assert.Equal(t, 201, resp.Status)
+}
+
+func TestConsoleColor(t *testing.T) {
+	for _, color := range []bool{false, true} {
+		out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+		require.NoError(t, err)
+		defer out.Close()
+
+		// a regular file isn't a terminal, so the colors are dropped unless forced
+		lab, err := newConsole(out, newTestConsole(t).resp, []string{filepath.Join(t.TempDir(), "httplab.json")}, nil)
+		require.NoError(t, err)
+		require.False(t, lab.color)
+		lab.color = color
+
+		srv := httptest.NewServer(NewHandler(lab, nil))
+		res, err := http.Get(srv.URL + "/colors")
+		require.NoError(t, err)
+		res.Body.Close()
+		srv.Close()
+
+		data, err := os.ReadFile(out.Name())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "/colors")
+		assert.Equal(t, color, strings.Contains(string(data), "\x1b["), "color: %v", color)
+	}
+}
+
+func TestRunHeadlessShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	args := cmdArgs{
+		port:    port,
+		status:  "200",
+		body:    "Hello",
+		configs: []string{filepath.Join(t.TempDir(), "httplab.json")},
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- runHeadless(args, func(next http.Handler) http.Handler { return next })
+	}()
+
+	eventually(t, func() bool {
+		res, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", port))
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	})
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("runHeadless didn't return on SIGINT")
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/user"
@@ -15,26 +17,162 @@ import (
 	"github.com/jroimartin/gocui"
 	"github.com/rs/cors"
 	flag "github.com/spf13/pflag"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // VERSION is the current version
-const VERSION = "v0.5.0-dev"
+const VERSION = httplab.Version
 
-// NewHandler returns a new http.Handler
-func NewHandler(ui *ui.UI, g *gocui.Gui) http.Handler {
+// Lab provides the responses to be served and gets notified about the incoming requests.
+type Lab interface {
+	Match(req *http.Request) (*httplab.Route, *httplab.Response)
+	AddRequest(req *httplab.Request) error
+	SetReply(req *httplab.Request, reply *httplab.Reply) error
+	Info(format string, args ...interface{})
+}
+
+// fullReplyLab is implemented by the Labs which need the whole body of the replies,
+// instead of the first MB kept by default.
+type fullReplyLab interface {
+	Lab
+	fullReplies()
+}
+
+// NewHandler returns a new http.Handler.
+// When proxy is set the requests are forwarded through it instead of served with the lab responses.
+func NewHandler(lab Lab, proxy *httplab.Proxy) http.Handler {
 	fn := func(w http.ResponseWriter, req *http.Request) {
-		if err := ui.AddRequest(g, req); err != nil {
-			ui.Info(g, "%v", err)
+		var (
+			route *httplab.Route
+			resp  *httplab.Response
+		)
+		if proxy == nil {
+			route, resp = lab.Match(req)
 		}
 
-		resp := ui.Response()
-		time.Sleep(resp.Delay)
-		resp.Write(w)
+		r, err := httplab.NewRequest(req)
+		if err != nil {
+			lab.Info("%v", err)
+		} else {
+			if proxy != nil {
+				r.Upstream = proxy.Target.String()
+			} else {
+				r.ServedBy(route, resp)
+			}
+			if err := lab.AddRequest(r); err != nil {
+				lab.Info("%v", err)
+			}
+		}
+
+		rec := httplab.NewReplyRecorder(w)
+		if _, ok := lab.(fullReplyLab); ok {
+			rec.MaxBody = -1
+		}
+		if proxy != nil {
+			proxy.ServeHTTP(rec, req)
+		} else if err := resp.Serve(rec, req); err != nil {
+			lab.Info("%v", err)
+		}
 
+		if r != nil {
+			if err := lab.SetReply(r, rec.Reply()); err != nil {
+				lab.Info("%v", err)
+			}
+		}
 	}
 	return http.HandlerFunc(fn)
 }
 
+// newProxy returns the proxy to --upstream, nil when it isn't set.
+// Labs implementing httplab.Interceptor can edit the upstream responses.
+func newProxy(args *cmdArgs, lab Lab) (*httplab.Proxy, error) {
+	if args.upstream == "" {
+		return nil, nil
+	}
+
+	proxy, err := httplab.NewProxy(args.upstream)
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := lab.(httplab.Interceptor); ok {
+		proxy.Interceptor = i
+	}
+	return proxy, nil
+}
+
+// tui is the Lab backed by the terminal UI.
+type tui struct {
+	ui *ui.UI
+	g  *gocui.Gui
+}
+
+func (t *tui) Match(req *http.Request) (*httplab.Route, *httplab.Response) {
+	return t.ui.Match(req)
+}
+
+func (t *tui) AddRequest(req *httplab.Request) error {
+	return t.ui.AddRequest(t.g, req)
+}
+
+func (t *tui) SetReply(req *httplab.Request, reply *httplab.Reply) error {
+	return t.ui.SetReply(t.g, req, reply)
+}
+
+func (t *tui) Intercepting(req *http.Request) bool {
+	return t.ui.Breaking()
+}
+
+func (t *tui) Intercept(req *http.Request, resp *httplab.Response) (*httplab.Response, error) {
+	return t.ui.Intercept(t.g, req, resp)
+}
+
+func (t *tui) Info(format string, args ...interface{}) {
+	t.ui.Info(t.g, format, args...)
+}
+
+func (t *tui) Response() *httplab.Response {
+	return t.ui.Response()
+}
+
+func (t *tui) SetResponse(resp *httplab.Response) {
+	t.ui.SetResponse(t.g, resp)
+}
+
+func (t *tui) Responses() map[string]*httplab.Response {
+	return t.ui.Responses()
+}
+
+func (t *tui) SaveResponse(name string, resp *httplab.Response) error {
+	return t.ui.SaveResponse(name, resp)
+}
+
+func (t *tui) DeleteResponse(name string) (bool, error) {
+	return t.ui.DeleteResponse(name)
+}
+
+func (t *tui) Requests() []*httplab.Request {
+	return t.ui.Requests()
+}
+
+func (t *tui) ResetRequests() error {
+	return t.ui.ResetRequests(t.g)
+}
+
+// systemConfigPath is the config file shared by every user, overlaid by their own.
+const systemConfigPath = "/etc/httplab"
+
+// userConfigPath returns the config file on $HOME, empty if the current user can't be found.
+func userConfigPath() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return u.HomeDir + "/.httplab"
+}
+
+// defaultConfigPath returns the config file new responses are saved into:
+// the one on the current directory if it exists, the one on $HOME otherwise.
 func defaultConfigPath() string {
 	var path = ".httplab"
 
@@ -42,17 +180,45 @@ func defaultConfigPath() string {
 		return path
 	}
 
-	u, err := user.Current()
-	if err != nil {
-		return path
+	if home := userConfigPath(); home != "" {
+		return home
 	}
+	return path
+}
+
+// defaultConfigPaths returns the config files loaded when --config isn't given, from the lowest precedence
+// to the highest: the system file, the user one and the one on the current directory.
+// Only the existing ones are returned, defaultConfigPath always comes last.
+func defaultConfigPaths() []string {
+	primary := defaultConfigPath()
+	info, _ := os.Stat(primary)
+
+	var paths []string
+	for _, path := range []string{systemConfigPath, userConfigPath(), ".httplab"} {
+		if path == "" {
+			continue
+		}
+		other, err := os.Stat(path)
+		// the current directory could be $HOME
+		if err != nil || info != nil && os.SameFile(info, other) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return append(paths, primary)
+}
 
-	return u.HomeDir + "/" + path
+// commands are the subcommands, invoked as the first argument.
+var commands = map[string]func(argv []string) error{
+	"config": runConfig,
+	"export": runExport,
+	"replay": runReplay,
 }
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nCommands:\n  config\t: Converts a config file between JSON, YAML and TOML\n  export\t: Exports a history file as curl commands, raw HTTP or HAR\n  replay\t: Replays a request of a history file to a target URL\n")
 	fmt.Fprintf(os.Stderr, "\nBindings:\n%s", ui.Bindings.Help())
 }
 
@@ -63,32 +229,81 @@ func Version() {
 }
 
 type cmdArgs struct {
-	autoUpdate  bool
-	body        string
-	config      string
-	corsEnabled bool
-	corsDisplay bool
-	delay       int
-	headers     []string
-	port        int
-	status      string
-	version     bool
+	adminHost        string
+	adminPort        int
+	autoUpdate       bool
+	body             string
+	cert             string
+	certDir          string
+	config           string
+	configs          []string
+	corsEnabled      bool
+	corsDisplay      bool
+	delay            int
+	envFile          string
+	headers          []string
+	h2c              bool
+	headless         bool
+	history          string
+	historyMax       int
+	historySize      int
+	historyTrunc     bool
+	http2            bool
+	key              string
+	playback         bool
+	port             int
+	record           bool
+	recordDuplicates string
+	status           string
+	tls              bool
+	upstream         string
+	version          bool
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				if err == flag.ErrHelp {
+					return
+				}
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
 	var args cmdArgs
 
 	flag.Usage = usage
 
+	flag.StringVar(&args.adminHost, "admin-host", "127.0.0.1", "Specifies the address where the admin API will bind to, it has no authentication.")
+	flag.IntVar(&args.adminPort, "admin-port", 0, "Specifies the port where the admin API will bind to, disabled by default.")
 	flag.BoolVarP(&args.autoUpdate, "auto-update", "a", true, "Auto-updates response when fields change.")
 	flag.StringVarP(&args.body, "body", "b", "Hello, World", "Specifies the initial response body.")
+	flag.StringVar(&args.cert, "cert", "", "Specifies the TLS certificate file, implies --tls.")
+	flag.StringVar(&args.certDir, "cert-dir", "", "Writes the generated TLS certificates into this directory.")
 	flag.StringVarP(&args.config, "config", "c", "", "Specifies custom config path.")
 	flag.BoolVar(&args.corsEnabled, "cors", false, "Enable CORS.")
 	flag.BoolVar(&args.corsDisplay, "cors-display", true, "Display CORS requests.")
 	flag.IntVarP(&args.delay, "delay", "d", 0, "Specifies the initial response delay in ms.")
+	flag.StringVar(&args.envFile, "env-file", "", "Loads the variables interpolated on the saved responses from this dotenv file.")
+	flag.BoolVar(&args.h2c, "h2c", false, "Serve cleartext HTTP/2 too, with prior knowledge or through \"Upgrade: h2c\".")
+	flag.StringVar(&args.history, "history", "", "Persists the captured requests into this JSON lines file.")
+	flag.IntVar(&args.historyMax, "history-max", 1000, "Rotates the history file after this amount of requests, 0 disables it.")
+	flag.IntVar(&args.historySize, "history-max-size", 0, "Rotates the history file when it exceeds this size in KB, 0 disables it.")
+	flag.BoolVar(&args.historyTrunc, "history-truncate", false, "Truncates the history file when the request history is reset.")
+	flag.BoolVar(&args.headless, "headless", false, "Don't start the UI, requests are printed to stdout.")
 	flag.StringSliceVarP(&args.headers, "headers", "H", []string{"X-Server:HTTPLab"}, "Specifies the initial response headers.")
+	flag.BoolVar(&args.http2, "http2", false, "Serve HTTP/2 over TLS too, implies --tls.")
+	flag.StringVar(&args.key, "key", "", "Specifies the TLS private key file, implies --tls.")
+	flag.BoolVar(&args.playback, "playback", false, "Only serves the recorded responses, the others are Not Found.")
 	flag.IntVarP(&args.port, "port", "p", 10080, "Specifies the port where HTTPLab will bind to.")
+	flag.BoolVar(&args.record, "record", false, "Saves every upstream response as a response with its route, requires --upstream.")
+	flag.StringVar(&args.recordDuplicates, "record-duplicates", "last", "Already recorded routes keep the first, last or a sequence of the responses.")
 	flag.StringVarP(&args.status, "status", "s", "200", "Specifies the initial response status.")
+	flag.BoolVar(&args.tls, "tls", false, "Serve HTTPS, a self-signed certificate is generated unless --cert and --key are given.")
+	flag.StringVar(&args.upstream, "upstream", "", "Forwards the requests to this URL, showing the real responses, instead of serving the configured ones.")
 	flag.BoolVarP(&args.version, "version", "v", false, "Prints current version.")
 
 	flag.Parse()
@@ -108,22 +323,57 @@ func main() {
 		}).Handler
 	}
 
+	// the responses are interpolated as they're loaded
+	if args.envFile != "" {
+		if err := httplab.LoadEnvFile(args.envFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if args.config == "" {
+		args.configs = defaultConfigPaths()
+		args.config = args.configs[len(args.configs)-1]
+	} else {
+		args.configs = []string{args.config}
+	}
+
+	if args.headless {
+		if err := runHeadless(args, middleware); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if srv, err := run(args, middleware); err != nil {
 		if err == gocui.ErrQuit {
 			log.Println("HTTPLab is shutting down")
-			srv.Shutdown(context.Background())
+			shutdown(srv)
 		} else {
 			log.Println(err)
 		}
 	}
 }
 
+// shutdownTimeout is how long the requests being served are waited for on shutdown.
+var shutdownTimeout = 5 * time.Second
+
+// shutdown stops srv gracefully, the requests still being served after shutdownTimeout,
+// like the hanging ones, have their connections closed.
+func shutdown(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Closing the connections still open: %v", err)
+		srv.Close()
+	}
+}
+
 func newResponse(args *cmdArgs) (*httplab.Response, error) {
 	resp, err := httplab.NewResponse(args.status, strings.Join(args.headers, "\n"), args.body)
 	if err != nil {
 		return nil, err
 	}
-	resp.Delay = time.Duration(args.delay) * time.Millisecond
+	resp.Delay = httplab.FixedDelay(time.Duration(args.delay) * time.Millisecond)
 	return resp, nil
 }
 
@@ -134,38 +384,131 @@ func run(args cmdArgs, middleware func(next http.Handler) http.Handler) (*http.S
 	}
 	defer g.Close()
 
-	if args.config == "" {
-		args.config = defaultConfigPath()
-	}
-
 	resp, err := newResponse(&args)
 	if err != nil {
 		return nil, err
 	}
 
-	ui := ui.New(resp, args.config)
+	ui := ui.New(resp, args.configs...)
 	ui.AutoUpdate = args.autoUpdate
+	ui.History = newHistory(&args)
+	ui.TruncateHistory = args.historyTrunc
+
+	// stops watching the config file once the UI is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	errCh, err := ui.Init(g)
+	errCh, err := ui.Init(ctx, g)
 	if err != nil {
 		return nil, err
 	}
 
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", args.port),
-		Handler: http.Handler(middleware(NewHandler(ui, g))),
+	lab := &tui{ui, g}
+	proxy, err := newProxy(&args, lab)
+	if err != nil {
+		return nil, err
+	}
+	ui.Upstream = args.upstream
+
+	handlerLab, err := newRecordingLab(&args, lab)
+	if err != nil {
+		return nil, err
+	}
+
+	srv, err := newServer(&args, middleware(NewHandler(handlerLab, proxy)), ui.Brokers)
+	if err != nil {
+		return nil, err
 	}
 
 	go func() {
 		// Make sure gocui has started
 		g.Update(func(g *gocui.Gui) error { return nil })
 
-		if err := srv.ListenAndServe(); err != nil {
+		ln, err := listen(srv)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		ui.Info(g, "Listening on :%d", listenPort(ln))
+
+		if err := srv.Serve(ln); err != http.ErrServerClosed {
 			errCh <- err
-		} else {
-			ui.Info(g, "Listening on :%d", args.port)
 		}
 	}()
 
+	if args.adminPort != 0 {
+		admin := newAdminServer(args.adminHost, args.adminPort, lab)
+		defer admin.Close()
+		go func() {
+			ln, err := net.Listen("tcp", admin.Addr)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := admin.Serve(ln); err != http.ErrServerClosed {
+				errCh <- err
+			}
+		}()
+	}
+
 	return srv, g.MainLoop()
 }
+
+func newHistory(args *cmdArgs) *httplab.History {
+	if args.history == "" {
+		return nil
+	}
+	return httplab.NewHistory(args.history, args.historyMax, int64(args.historySize)<<10)
+}
+
+// newServer returns the server of the lab handler, serving the SSE and WebSocket responses through brokers.
+func newServer(args *cmdArgs, handler http.Handler, brokers *httplab.Brokers) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:        fmt.Sprintf(":%d", args.port),
+		Handler:     handler,
+		BaseContext: brokers.BaseContext,
+		ConnContext: httplab.ConnContext,
+	}
+	// the SSE streams would keep the server from shutting down, and the WebSocket connections open
+	srv.RegisterOnShutdown(brokers.Close)
+
+	if args.tls || args.http2 || args.cert != "" || args.key != "" {
+		var err error
+		srv.TLSConfig, err = httplab.NewTLSConfig(args.cert, args.key, args.certDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Offering h2 is enough for the server to negotiate it on the TLS connections of the listener
+	// since Go 1.27, registering an http2.Server on TLSNextProto instead would only take *tls.Conn ones.
+	if args.http2 {
+		srv.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+	if args.h2c {
+		srv.Handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	return srv, nil
+}
+
+// listen binds the address of srv, the listener returned is ready to be served.
+func listen(srv *http.Server) (net.Listener, error) {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if srv.TLSConfig != nil {
+		ln = tls.NewListener(ln, srv.TLSConfig)
+	}
+	return httplab.NewListener(ln), nil
+}
+
+// listenPort returns the port ln is bound to.
+func listenPort(ln net.Listener) int {
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gchaincl/httplab"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventually fails the test unless cond becomes true within a second.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+func TestHandlerUpstream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "from upstream "+req.URL.Path)
+	}))
+	defer upstream.Close()
+
+	lab := newTestConsole(t)
+	proxy, err := newProxy(&cmdArgs{upstream: upstream.URL}, lab)
+	require.NoError(t, err)
+	require.Nil(t, proxy.Interceptor)
+
+	srv := httptest.NewServer(NewHandler(lab, proxy))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/foo")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "from upstream /foo", string(body))
+
+	// the reply is recorded once it's been sent
+	eventually(t, func() bool {
+		reqs := lab.Requests()
+		return len(reqs) == 1 && reqs[0].Reply != nil
+	})
+
+	reqs := lab.Requests()
+	assert.Equal(t, upstream.URL, reqs[0].Upstream)
+	assert.Empty(t, reqs[0].Response)
+	require.NotNil(t, reqs[0].Reply)
+	assert.Equal(t, http.StatusAccepted, reqs[0].Reply.Status)
+	assert.Equal(t, "from upstream /foo", string(reqs[0].Reply.Body))
+
+	proxy, err = newProxy(&cmdArgs{}, lab)
+	require.NoError(t, err)
+	assert.Nil(t, proxy)
+}
+
+func TestHandlerWebSocket(t *testing.T) {
+	lab := newTestConsole(t)
+	lab.resp.WebSocket = &httplab.WebSocket{Echo: true}
+
+	srv := httptest.NewServer(NewHandler(lab, nil))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	conn.Close()
+
+	reqs := lab.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "websocket", reqs[0].Header().Get("Upgrade"))
+}
+
+func TestShutdownClosesHangingRequests(t *testing.T) {
+	defer func(timeout time.Duration) { shutdownTimeout = timeout }(shutdownTimeout)
+	shutdownTimeout = 50 * time.Millisecond
+
+	entered := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(entered)
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := http.Get(srv.URL)
+		done <- err
+	}()
+	<-entered
+
+	start := time.Now()
+	shutdown(srv.Config)
+	assert.True(t, time.Since(start) < time.Second, "shutdown took %v", time.Since(start))
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the hanging request wasn't closed")
+	}
+}
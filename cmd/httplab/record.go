@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gchaincl/httplab"
+)
+
+// recordingLab records the replies of the requests it's notified about.
+type recordingLab struct {
+	Lab
+	recorder *httplab.Recorder
+}
+
+// fullReplies makes the handler keep the whole body of the replies, so they're recorded as they were sent.
+func (l *recordingLab) fullReplies() {}
+
+func (l *recordingLab) SetReply(req *httplab.Request, reply *httplab.Reply) error {
+	if err := l.Lab.SetReply(req, reply); err != nil {
+		return err
+	}
+
+	name, err := l.recorder.Record(req)
+	if err != nil {
+		return fmt.Errorf("Record: %v", err)
+	}
+	if name != "" {
+		l.Info("Recorded '%s'", name)
+	}
+	return nil
+}
+
+// playbackLab only serves the responses matched by a route.
+type playbackLab struct {
+	Lab
+}
+
+func (l *playbackLab) Match(req *http.Request) (*httplab.Route, *httplab.Response) {
+	if route, resp := l.Lab.Match(req); route != nil {
+		return route, resp
+	}
+
+	resp, _ := httplab.NewResponse("404", "Content-Type: text/plain; charset=utf-8",
+		fmt.Sprintf("No recording for %s %s\n", req.Method, req.URL.Path))
+	return nil, resp
+}
+
+// newRecordingLab decorates lab according to the record and playback flags.
+func newRecordingLab(args *cmdArgs, lab Lab) (Lab, error) {
+	switch {
+	case args.record && args.playback:
+		return nil, fmt.Errorf("--record and --playback can't be combined")
+	case args.record:
+		if args.upstream == "" {
+			return nil, fmt.Errorf("--record requires --upstream")
+		}
+		mode, err := httplab.ParseDuplicateMode(args.recordDuplicates)
+		if err != nil {
+			return nil, err
+		}
+		return &recordingLab{lab, httplab.NewRecorder(args.config, mode)}, nil
+	case args.playback:
+		if args.upstream != "" {
+			return nil, fmt.Errorf("--playback can't be combined with --upstream")
+		}
+		return &playbackLab{lab}, nil
+	}
+	return lab, nil
+}
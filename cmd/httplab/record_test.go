@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gchaincl/httplab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordAndPlayback(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/big" {
+			// larger than the body kept by default
+			io.WriteString(w, strings.Repeat("x", 2<<20))
+			return
+		}
+		io.WriteString(w, "upstream "+req.URL.Path)
+	}))
+	defer upstream.Close()
+
+	config := filepath.Join(t.TempDir(), "httplab.json")
+	args := &cmdArgs{config: config, upstream: upstream.URL, record: true, recordDuplicates: "sequence"}
+
+	lab, err := newConsole(os.Stdout, nil, []string{config}, nil)
+	require.NoError(t, err)
+	lab.out = io.Discard
+
+	proxy, err := newProxy(args, lab)
+	require.NoError(t, err)
+	recording, err := newRecordingLab(args, lab)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(NewHandler(recording, proxy))
+	defer srv.Close()
+	get(t, srv.URL+"/a")
+	get(t, srv.URL+"/b")
+	get(t, srv.URL+"/big")
+
+	// recordings are saved once the reply has been sent
+	eventually(t, func() bool {
+		rl := httplab.NewResponsesList()
+		return rl.Load(config) == nil && rl.Len() == 3
+	})
+
+	args = &cmdArgs{config: config, playback: true}
+	lab, err = newConsole(os.Stdout, nil, []string{config}, nil)
+	require.NoError(t, err)
+	lab.out = io.Discard
+	playback, err := newRecordingLab(args, lab)
+	require.NoError(t, err)
+
+	srv = httptest.NewServer(NewHandler(playback, nil))
+	defer srv.Close()
+
+	status, body := get(t, srv.URL+"/a")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "upstream /a", body)
+
+	_, body = get(t, srv.URL+"/big")
+	assert.Equal(t, 2<<20, len(body))
+
+	status, body = get(t, srv.URL+"/c")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "No recording for GET /c\n", body)
+}
+
+func TestNewRecordingLabFlags(t *testing.T) {
+	lab := newTestConsole(t)
+	for _, args := range []cmdArgs{
+		{record: true},
+		{record: true, playback: true, upstream: "http://localhost"},
+		{playback: true, upstream: "http://localhost"},
+		{record: true, upstream: "http://localhost", recordDuplicates: "all"},
+	} {
+		_, err := newRecordingLab(&args, lab)
+		assert.Error(t, err, "%+v", args)
+	}
+
+	l, err := newRecordingLab(&cmdArgs{}, lab)
+	require.NoError(t, err)
+	assert.Equal(t, lab, l)
+}
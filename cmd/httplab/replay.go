@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gchaincl/httplab"
+	flag "github.com/spf13/pflag"
+)
+
+func runReplay(argv []string) error {
+	var (
+		index    int
+		headers  []string
+		insecure bool
+		record   bool
+	)
+
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s replay [flags] <history file> <target URL>:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.StringArrayVarP(&headers, "header", "H", nil, "Overrides a request header, 'Name:' removes it.")
+	fs.IntVarP(&index, "index", "i", -1, "Replays this request, starting at 1. Negative values count from the last one.")
+	fs.BoolVarP(&insecure, "insecure", "k", false, "Doesn't verify the target TLS certificate.")
+	fs.BoolVar(&record, "record", true, "Appends the replay to the history file.")
+	if err := fs.Parse(argv); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("replay: a history file and a target URL are required")
+	}
+
+	history := httplab.NewHistory(fs.Arg(0), 0, 0)
+	reqs, err := history.Load()
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectRequests(reqs, []int{index})
+	if err != nil {
+		return err
+	}
+
+	overrides, err := httplab.ParseHeaderOverrides(headers)
+	if err != nil {
+		return err
+	}
+
+	var client *http.Client
+	if insecure {
+		client = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	sent, err := selected[0].Replay(context.Background(), client, fs.Arg(1), overrides)
+	if err != nil {
+		return err
+	}
+
+	if record {
+		if err := history.Append(sent); err != nil {
+			return err
+		}
+	}
+
+	buf, err := sent.Reply.Dump()
+	if err != nil {
+		return err
+	}
+	if !isTerminal(os.Stdout) {
+		buf = httplab.Decolorize(buf)
+	}
+	_, err = fmt.Fprintf(os.Stdout, "%s\n", buf)
+	return err
+}
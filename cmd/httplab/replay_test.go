@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gchaincl/httplab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayCommand(t *testing.T) {
+	var got *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req
+		io.WriteString(w, "upstream")
+	}))
+	defer upstream.Close()
+
+	history := httplab.NewHistory(filepath.Join(t.TempDir(), "history"), 0, 0)
+	require.NoError(t, history.Append(&httplab.Request{
+		Method:  "PUT",
+		URL:     "/first",
+		Headers: []httplab.HeaderField{{Name: "X-Token", Value: "old"}},
+	}))
+	require.NoError(t, history.Append(&httplab.Request{Method: "GET", URL: "/second"}))
+
+	require.NoError(t, runReplay([]string{"-i", "1", "-H", "X-Token: new, really", history.Path, upstream.URL}))
+	assert.Equal(t, "PUT", got.Method)
+	assert.Equal(t, "/first", got.URL.Path)
+	assert.Equal(t, "new, really", got.Header.Get("X-Token"))
+
+	reqs, err := history.Load()
+	require.NoError(t, err)
+	require.Len(t, reqs, 3)
+	assert.Equal(t, upstream.URL+"/first", reqs[2].ReplayedTo)
+	assert.Equal(t, "upstream", string(reqs[2].Reply.Body))
+
+	require.NoError(t, runReplay([]string{"--record=false", history.Path, upstream.URL}))
+	assert.Equal(t, "/first", got.URL.Path, "replays the last request by default")
+
+	reqs, err = history.Load()
+	require.NoError(t, err)
+	assert.Len(t, reqs, 3)
+
+	assert.Error(t, runReplay([]string{history.Path}))
+}
@@ -0,0 +1,184 @@
+package httplab
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// maxConnBuffer is the amount of bytes kept per connection to look for request headers.
+const maxConnBuffer = 64 << 10
+
+type connKey struct{}
+
+// Listener wraps a net.Listener keeping track of the raw bytes received on each connection,
+// so that captured requests preserve the original order of their headers.
+// Servers using it must set ConnContext as their http.Server.ConnContext.
+// It wraps TLS listeners too, its TLS connections are seen by servers as such: they set
+// the Request.TLS of their requests and negotiate HTTP/2 through ALPN.
+type Listener struct {
+	net.Listener
+}
+
+// NewListener wraps l.
+func NewListener(l net.Listener) *Listener {
+	return &Listener{l}
+}
+
+// Accept waits for and returns the next connection to the listener.
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if tc, ok := c.(*tls.Conn); ok {
+		return &tlsConn{conn: &conn{Conn: c}, tls: tc}, nil
+	}
+	return &conn{Conn: c}, nil
+}
+
+// ConnContext makes the connection accessible from the context of its requests.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	switch c := c.(type) {
+	case *conn:
+		return context.WithValue(ctx, connKey{}, c)
+	case *tlsConn:
+		return context.WithValue(ctx, connKey{}, c.conn)
+	}
+	return ctx
+}
+
+// tlsConn is a conn over TLS, net/http servers perform its handshake and
+// take its state through the ConnectionState and HandshakeContext methods,
+// serving HTTP/2 on it requires Go 1.27 (see go.mod).
+type tlsConn struct {
+	*conn
+	tls *tls.Conn
+}
+
+// ConnectionState returns the state of the TLS connection.
+func (c *tlsConn) ConnectionState() tls.ConnectionState {
+	return c.tls.ConnectionState()
+}
+
+// HandshakeContext runs the TLS handshake, if it hasn't been run yet.
+func (c *tlsConn) HandshakeContext(ctx context.Context) error {
+	return c.tls.HandshakeContext(ctx)
+}
+
+type conn struct {
+	net.Conn
+
+	mu  sync.Mutex
+	buf []byte
+	// h2 follows the frames once the connection switches to HTTP/2.
+	h2 *http2Frames
+}
+
+func (c *conn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+
+	c.mu.Lock()
+	if c.h2 != nil {
+		c.h2.write(p[:n])
+		c.mu.Unlock()
+		return n, err
+	}
+
+	c.buf = append(c.buf, p[:n]...)
+	if i := bytes.Index(c.buf, []byte(http2Preface)); i >= 0 {
+		c.h2 = newHTTP2Frames()
+		c.h2.write(c.buf[i+len(http2Preface):])
+		c.buf = c.buf[:i]
+	} else if len(c.buf) > maxConnBuffer {
+		c.buf = c.buf[len(c.buf)-maxConnBuffer:]
+	}
+	c.mu.Unlock()
+
+	return n, err
+}
+
+// NetConn returns the wrapped connection.
+func (c *conn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// tlsState returns the state of the TLS connection, nil if it isn't one.
+func (c *conn) tlsState() *tls.ConnectionState {
+	if tc, ok := c.Conn.(*tls.Conn); ok {
+		state := tc.ConnectionState()
+		return &state
+	}
+	return nil
+}
+
+// headerOrder returns the header names of req in the order they were received,
+// and discards the bytes received up to the end of its header block.
+// It returns nil if the header block can't be found.
+func (c *conn) headerOrder(req *http.Request) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reqLine := []byte(req.Method + " " + req.RequestURI + " " + req.Proto + "\r\n")
+	start := bytes.Index(c.buf, reqLine)
+	if start == -1 {
+		return nil
+	}
+	start += len(reqLine)
+
+	end := bytes.Index(c.buf[start:], []byte("\r\n\r\n"))
+	if end == -1 {
+		return nil
+	}
+	end += start
+
+	var names []string
+	for _, line := range bytes.Split(c.buf[start:end], []byte("\r\n")) {
+		// skip obsolete line folding
+		if len(line) == 0 || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		if i := bytes.IndexByte(line, ':'); i > 0 {
+			names = append(names, http.CanonicalHeaderKey(string(bytes.TrimSpace(line[:i]))))
+		}
+	}
+
+	c.buf = c.buf[end+4:]
+	return names
+}
+
+// http2Headers returns the stream ID and the pseudo-headers of req, an HTTP/2 request,
+// along with its header names in the order they were received.
+// The stream ID is 0 and the pseudo-headers are rebuilt when its header block is unknown.
+func (c *conn) http2Headers(req *http.Request) (uint32, []HeaderField, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var block *headerBlock
+	if c.h2 != nil {
+		block = c.h2.claim(req)
+	}
+	if block == nil {
+		return 0, pseudoHeaders(req, c.tlsState() != nil), nil
+	}
+
+	var pseudo []HeaderField
+	var names []string
+	for _, f := range block.fields {
+		if f.IsPseudo() {
+			pseudo = append(pseudo, HeaderField{f.Name, f.Value})
+		} else {
+			names = append(names, http.CanonicalHeaderKey(f.Name))
+		}
+	}
+	return block.streamID, pseudo, names
+}
+
+func connFromContext(ctx context.Context) *conn {
+	c, _ := ctx.Value(connKey{}).(*conn)
+	return c
+}
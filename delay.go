@@ -0,0 +1,314 @@
+package httplab
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DelayDistribution is how a Delay picks the time to wait before each response.
+type DelayDistribution string
+
+// DelayDistribution values
+const (
+	// DelayFixed always waits Value.
+	DelayFixed DelayDistribution = "fixed"
+	// DelayUniform waits between Min and Max.
+	DelayUniform DelayDistribution = "uniform"
+	// DelayNormal follows a normal distribution of Mean and StdDev, never waiting less than 0.
+	DelayNormal DelayDistribution = "normal"
+	// DelayPercentiles interpolates between latency percentiles, like p50=100ms p99=1s.
+	DelayPercentiles DelayDistribution = "percentiles"
+)
+
+// Percentile is the latency P percent of the responses stay under.
+type Percentile struct {
+	P     float64
+	Value time.Duration
+}
+
+// Delay is the time a response waits before being sent.
+//
+// In the config it's either a duration like "250ms" or "2s", a number of milliseconds,
+// or an object describing a distribution:
+//
+//	{"Distribution": "uniform", "Min": "100ms", "Max": "300ms"}
+//	{"Distribution": "normal", "Mean": "200ms", "StdDev": "50ms"}
+//	{"Distribution": "percentiles", "Percentiles": {"p50": "100ms", "p90": "300ms", "p99": "1s"}}
+type Delay struct {
+	Distribution DelayDistribution
+	Value        time.Duration
+	Min, Max     time.Duration
+	Mean, StdDev time.Duration
+	// Percentiles are sorted by P. Below the first one the delay goes from 0, above the last one it is the last value.
+	Percentiles []Percentile
+}
+
+// FixedDelay returns a Delay always waiting d.
+func FixedDelay(d time.Duration) Delay {
+	return Delay{Distribution: DelayFixed, Value: d}
+}
+
+// Duration picks the time to wait before the next response.
+func (d Delay) Duration() time.Duration {
+	switch d.Distribution {
+	case DelayUniform:
+		if d.Max <= d.Min {
+			return d.Min
+		}
+		return d.Min + time.Duration(rand.Int63n(int64(d.Max-d.Min)+1))
+	case DelayNormal:
+		v := float64(d.Mean) + rand.NormFloat64()*float64(d.StdDev)
+		return time.Duration(math.Max(v, 0))
+	case DelayPercentiles:
+		return d.percentile(rand.Float64() * 100)
+	}
+	return d.Value
+}
+
+// percentile interpolates the delay at p, between 0 and 100.
+func (d Delay) percentile(p float64) time.Duration {
+	prev := Percentile{}
+	for _, cur := range d.Percentiles {
+		if p <= cur.P {
+			if cur.P == prev.P {
+				return cur.Value
+			}
+			ratio := (p - prev.P) / (cur.P - prev.P)
+			return prev.Value + time.Duration(ratio*float64(cur.Value-prev.Value))
+		}
+		prev = cur
+	}
+	return prev.Value
+}
+
+// String formats the delay the way ParseDelay reads it.
+func (d Delay) String() string {
+	switch d.Distribution {
+	case DelayUniform:
+		return fmt.Sprintf("uniform %s %s", d.Min, d.Max)
+	case DelayNormal:
+		return fmt.Sprintf("normal %s %s", d.Mean, d.StdDev)
+	case DelayPercentiles:
+		var ps []string
+		for _, p := range d.Percentiles {
+			ps = append(ps, fmt.Sprintf("p%s=%s", strconv.FormatFloat(p.P, 'f', -1, 64), p.Value))
+		}
+		return strings.Join(ps, " ")
+	}
+	return d.Value.String()
+}
+
+// ParseDelay parses a delay as written by Delay.String, a plain number is taken as milliseconds:
+//
+//	250, 250ms, 2s
+//	uniform 100ms 300ms
+//	normal 200ms 50ms
+//	p50=100ms p90=300ms p99=1s
+func ParseDelay(s string) (Delay, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return FixedDelay(0), nil
+	}
+
+	switch fields[0] {
+	case string(DelayUniform), string(DelayNormal):
+		if len(fields) != 3 {
+			return Delay{}, fmt.Errorf("Delay: expected '%s <duration> <duration>'", fields[0])
+		}
+		a, err := parseDuration(fields[1])
+		if err != nil {
+			return Delay{}, err
+		}
+		b, err := parseDuration(fields[2])
+		if err != nil {
+			return Delay{}, err
+		}
+		if fields[0] == string(DelayUniform) {
+			return Delay{Distribution: DelayUniform, Min: a, Max: b}, nil
+		}
+		return Delay{Distribution: DelayNormal, Mean: a, StdDev: b}, nil
+	}
+
+	if strings.HasPrefix(fields[0], "p") {
+		ps := make(map[string]time.Duration)
+		for _, field := range fields {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return Delay{}, fmt.Errorf("Delay: expected 'p<percentile>=<duration>', got '%s'", field)
+			}
+			v, err := parseDuration(kv[1])
+			if err != nil {
+				return Delay{}, err
+			}
+			ps[kv[0]] = v
+		}
+		return percentilesDelay(ps)
+	}
+
+	if len(fields) != 1 {
+		return Delay{}, fmt.Errorf("Delay: can't parse '%s'", s)
+	}
+	v, err := parseDuration(fields[0])
+	if err != nil {
+		return Delay{}, err
+	}
+	return FixedDelay(v), nil
+}
+
+// parseDuration parses a time.Duration, a plain number is taken as milliseconds.
+func parseDuration(s string) (time.Duration, error) {
+	if ms, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("Delay: can't parse '%s' as a duration", s)
+	}
+	return d, nil
+}
+
+func percentilesDelay(ps map[string]time.Duration) (Delay, error) {
+	d := Delay{Distribution: DelayPercentiles}
+	for name, v := range ps {
+		p, err := strconv.ParseFloat(strings.TrimPrefix(name, "p"), 64)
+		if err != nil || !strings.HasPrefix(name, "p") || p < 0 || p > 100 {
+			return Delay{}, fmt.Errorf("Delay: invalid percentile '%s', expected p0 to p100", name)
+		}
+		d.Percentiles = append(d.Percentiles, Percentile{p, v})
+	}
+	if len(d.Percentiles) == 0 {
+		return Delay{}, fmt.Errorf("Delay: percentiles are missing")
+	}
+
+	sort.Slice(d.Percentiles, func(i, j int) bool {
+		return d.Percentiles[i].P < d.Percentiles[j].P
+	})
+	for i := 1; i < len(d.Percentiles); i++ {
+		if d.Percentiles[i].Value < d.Percentiles[i-1].Value {
+			return Delay{}, fmt.Errorf("Delay: p%v can't be lower than p%v", d.Percentiles[i].P, d.Percentiles[i-1].P)
+		}
+	}
+	return d, nil
+}
+
+// jsonDuration is a duration in the config, a string like "250ms" or a number of milliseconds.
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var s string
+	switch v := v.(type) {
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("Delay: expected a duration like \"250ms\" or a number of milliseconds")
+	}
+
+	parsed, err := parseDuration(s)
+	*d = jsonDuration(parsed)
+	return err
+}
+
+// jsonDelay is the config representation of a distribution.
+type jsonDelay struct {
+	Distribution DelayDistribution
+	Value        *jsonDuration           `json:",omitempty"`
+	Min          *jsonDuration           `json:",omitempty"`
+	Max          *jsonDuration           `json:",omitempty"`
+	Mean         *jsonDuration           `json:",omitempty"`
+	StdDev       *jsonDuration           `json:",omitempty"`
+	Percentiles  map[string]jsonDuration `json:",omitempty"`
+}
+
+func durationPtr(d time.Duration) *jsonDuration {
+	v := jsonDuration(d)
+	return &v
+}
+
+// MarshalJSON writes a fixed delay as a duration string, and distributions as objects.
+func (d Delay) MarshalJSON() ([]byte, error) {
+	switch d.Distribution {
+	case DelayUniform:
+		return json.Marshal(jsonDelay{Distribution: d.Distribution, Min: durationPtr(d.Min), Max: durationPtr(d.Max)})
+	case DelayNormal:
+		return json.Marshal(jsonDelay{Distribution: d.Distribution, Mean: durationPtr(d.Mean), StdDev: durationPtr(d.StdDev)})
+	case DelayPercentiles:
+		v := jsonDelay{Distribution: d.Distribution, Percentiles: make(map[string]jsonDuration)}
+		for _, p := range d.Percentiles {
+			v.Percentiles["p"+strconv.FormatFloat(p.P, 'f', -1, 64)] = jsonDuration(p.Value)
+		}
+		return json.Marshal(v)
+	}
+	return jsonDuration(d.Value).MarshalJSON()
+}
+
+// UnmarshalJSON reads a duration, a number of milliseconds or a distribution object.
+func (d *Delay) UnmarshalJSON(data []byte) error {
+	if trimmed := strings.TrimSpace(string(data)); !strings.HasPrefix(trimmed, "{") {
+		var v jsonDuration
+		if err := v.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		*d = FixedDelay(time.Duration(v))
+		return nil
+	}
+
+	var v jsonDelay
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	get := func(name string, d *jsonDuration) (time.Duration, error) {
+		if d == nil {
+			return 0, fmt.Errorf("Delay: %s distribution requires %s", v.Distribution, name)
+		}
+		return time.Duration(*d), nil
+	}
+
+	var err error
+	switch v.Distribution {
+	case DelayFixed, "":
+		var value time.Duration
+		value, err = get("Value", v.Value)
+		*d = FixedDelay(value)
+	case DelayUniform:
+		*d = Delay{Distribution: DelayUniform}
+		if d.Min, err = get("Min", v.Min); err == nil {
+			d.Max, err = get("Max", v.Max)
+		}
+		if err == nil && d.Max < d.Min {
+			err = fmt.Errorf("Delay: Max can't be lower than Min")
+		}
+	case DelayNormal:
+		*d = Delay{Distribution: DelayNormal}
+		if d.Mean, err = get("Mean", v.Mean); err == nil {
+			d.StdDev, err = get("StdDev", v.StdDev)
+		}
+	case DelayPercentiles:
+		ps := make(map[string]time.Duration)
+		for name, value := range v.Percentiles {
+			ps[name] = time.Duration(value)
+		}
+		*d, err = percentilesDelay(ps)
+	default:
+		err = fmt.Errorf("Delay: unknown distribution '%s', expected fixed, uniform, normal or percentiles", v.Distribution)
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package httplab
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDelay(t *testing.T) {
+	for s, expected := range map[string]Delay{
+		"":                  FixedDelay(0),
+		"250":               FixedDelay(250 * time.Millisecond),
+		" 250ms\n":          FixedDelay(250 * time.Millisecond),
+		"2s":                FixedDelay(2 * time.Second),
+		"uniform 100ms 300": {Distribution: DelayUniform, Min: 100 * time.Millisecond, Max: 300 * time.Millisecond},
+		"normal 200ms 50ms": {Distribution: DelayNormal, Mean: 200 * time.Millisecond, StdDev: 50 * time.Millisecond},
+		"p99=1s p50=100ms p90=300ms": {Distribution: DelayPercentiles, Percentiles: []Percentile{
+			{50, 100 * time.Millisecond}, {90, 300 * time.Millisecond}, {99, time.Second},
+		}},
+	} {
+		d, err := ParseDelay(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, d, s)
+
+		// String has to be parsed back
+		again, err := ParseDelay(d.String())
+		require.NoError(t, err, d.String())
+		assert.Equal(t, d, again)
+	}
+
+	for _, s := range []string{"foo", "1s 2s", "uniform 1s", "normal x 1s", "p50", "p101=1s", "p50=1s p90=10ms"} {
+		_, err := ParseDelay(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestDelayJSON(t *testing.T) {
+	for data, expected := range map[string]Delay{
+		`1000`:    FixedDelay(time.Second),
+		`"250ms"`: FixedDelay(250 * time.Millisecond),
+		`{"Distribution": "fixed", "Value": "1s"}`:                 FixedDelay(time.Second),
+		`{"Distribution": "uniform", "Min": 100, "Max": "300ms"}`:  {Distribution: DelayUniform, Min: 100 * time.Millisecond, Max: 300 * time.Millisecond},
+		`{"Distribution": "normal", "Mean": "1s", "StdDev": "0s"}`: {Distribution: DelayNormal, Mean: time.Second},
+		`{"Distribution": "percentiles", "Percentiles": {"p50": "100ms", "p0": 10}}`: {Distribution: DelayPercentiles, Percentiles: []Percentile{
+			{0, 10 * time.Millisecond}, {50, 100 * time.Millisecond},
+		}},
+	} {
+		var d Delay
+		require.NoError(t, json.Unmarshal([]byte(data), &d), data)
+		assert.Equal(t, expected, d, data)
+
+		buf, err := json.Marshal(d)
+		require.NoError(t, err)
+		var again Delay
+		require.NoError(t, json.Unmarshal(buf, &again), string(buf))
+		assert.Equal(t, d, again)
+	}
+
+	buf, err := json.Marshal(FixedDelay(1500 * time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, `"1.5s"`, string(buf))
+
+	for _, data := range []string{
+		`"forever"`,
+		`true`,
+		`{"Distribution": "uniform", "Min": "1s"}`,
+		`{"Distribution": "uniform", "Min": "2s", "Max": "1s"}`,
+		`{"Distribution": "exponential"}`,
+		`{"Distribution": "percentiles"}`,
+	} {
+		var d Delay
+		assert.Error(t, json.Unmarshal([]byte(data), &d), data)
+	}
+}
+
+func TestDelayDuration(t *testing.T) {
+	assert.Equal(t, time.Second, FixedDelay(time.Second).Duration())
+	assert.Equal(t, time.Duration(0), Delay{}.Duration())
+
+	uniform := Delay{Distribution: DelayUniform, Min: 100 * time.Millisecond, Max: 200 * time.Millisecond}
+	normal := Delay{Distribution: DelayNormal, Mean: 10 * time.Millisecond, StdDev: 20 * time.Millisecond}
+	for i := 0; i < 1000; i++ {
+		d := uniform.Duration()
+		assert.True(t, d >= uniform.Min && d <= uniform.Max, "%s out of range", d)
+		assert.True(t, normal.Duration() >= 0)
+	}
+
+	ps := Delay{Distribution: DelayPercentiles, Percentiles: []Percentile{
+		{50, 100 * time.Millisecond}, {90, 300 * time.Millisecond}, {99, time.Second},
+	}}
+	assert.Equal(t, time.Duration(0), ps.percentile(0))
+	assert.Equal(t, 50*time.Millisecond, ps.percentile(25))
+	assert.Equal(t, 100*time.Millisecond, ps.percentile(50))
+	assert.Equal(t, 200*time.Millisecond, ps.percentile(70))
+	assert.Equal(t, time.Second, ps.percentile(99))
+	assert.Equal(t, time.Second, ps.percentile(100))
+}
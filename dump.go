@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"regexp"
 	"sort"
@@ -29,58 +28,102 @@ func withColor(color int, text string) string {
 	return fmt.Sprintf("\x1b[0;%dm%s\x1b[0;0m", color, text)
 }
 
-func writeBody(buf *bytes.Buffer, req *http.Request) error {
-	body, err := io.ReadAll(req.Body)
-	if err != nil {
-		return err
-	}
-
+func writeBody(buf *bytes.Buffer, contentType string, body []byte) error {
 	if len(body) > 0 {
 		buf.WriteRune('\n')
 	}
 
-	if strings.Contains(req.Header.Get("Content-Type"), "application/json") {
+	if strings.Contains(contentType, "application/json") {
 		if err := json.Indent(buf, body, "", "  "); err == nil {
 			return nil
 		}
 	}
 
-	_, err = buf.Write(body)
+	_, err := buf.Write(body)
 	return err
 }
 
+func writeHeaders(buf *bytes.Buffer, fields []HeaderField) {
+	headers := append([]HeaderField(nil), fields...)
+	sort.SliceStable(headers, func(i, j int) bool {
+		return headers[i].Name < headers[j].Name
+	})
+	for _, h := range headers {
+		fmt.Fprintf(buf, "%s: %s\n", withColor(31, h.Name), withColor(32, h.Value))
+	}
+}
+
 // DumpRequest pretty prints an http.Request
 func DumpRequest(req *http.Request) ([]byte, error) {
-	buf := bytes.NewBuffer(nil)
-
-	reqURI := req.RequestURI
-	if reqURI == "" {
-		reqURI = req.URL.RequestURI()
+	r, err := NewRequest(req)
+	if err != nil {
+		return nil, err
 	}
+	return r.Dump()
+}
 
-	fmt.Fprintf(buf, "%s %s %s/%d.%d\n",
-		withColor(35, valueOrDefault(req.Method, "GET")),
-		reqURI,
+// Dump pretty prints the request, headers are sorted by name.
+func (r *Request) Dump() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+
+	proto := strings.TrimPrefix(r.Proto, "HTTP")
+	fmt.Fprintf(buf, "%s %s %s%s\n",
+		withColor(35, r.Method),
+		r.URL,
 		withColor(35, "HTTP"),
-		req.ProtoMajor,
-		req.ProtoMinor,
+		proto,
 	)
 
-	keys := sortedHeaderKeys(req)
-	for _, key := range keys {
-		val := req.Header.Get(key)
-		fmt.Fprintf(buf, "%s: %s\n", withColor(31, key), withColor(32, val))
+	if r.TLS != nil {
+		fmt.Fprintf(buf, "%s: %s, %s, SNI=%s\n",
+			withColor(36, "TLS"),
+			r.TLS.Version,
+			r.TLS.CipherSuite,
+			valueOrDefault(r.TLS.ServerName, "-"),
+		)
+	}
+
+	if r.StreamID != 0 {
+		fmt.Fprintf(buf, "%s: %d\n", withColor(36, "Stream"), r.StreamID)
+	}
+	// pseudo-headers keep their order, they come first on the wire
+	for _, h := range r.PseudoHeaders {
+		fmt.Fprintf(buf, "%s: %s\n", withColor(31, h.Name), withColor(32, h.Value))
 	}
 
-	err := writeBody(buf, req)
+	writeHeaders(buf, r.Headers)
+	err := writeBody(buf, r.Header().Get("Content-Type"), r.Body)
 	return buf.Bytes(), err
 }
 
-func sortedHeaderKeys(req *http.Request) []string {
-	var keys []string
-	for k := range req.Header {
-		keys = append(keys, k)
+// Dump pretty prints the reply, headers are sorted by name.
+func (r *Reply) Dump() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+
+	proto := strings.TrimPrefix(valueOrDefault(r.Proto, "HTTP/1.1"), "HTTP")
+	fmt.Fprintf(buf, "%s%s %s %s\n",
+		withColor(35, "HTTP"),
+		proto,
+		withColor(35, fmt.Sprint(r.Status)),
+		http.StatusText(r.Status),
+	)
+
+	writeHeaders(buf, r.Headers)
+
+	var contentType string
+	for _, h := range r.Headers {
+		if h.Name == "Content-Type" {
+			contentType = h.Value
+		}
+	}
+	err := writeBody(buf, contentType, r.Body)
+	if int64(len(r.Body)) < r.Size {
+		fmt.Fprintf(buf, "\n... %d more bytes", r.Size-int64(len(r.Body)))
 	}
-	sort.Strings(keys)
-	return keys
+
+	if len(r.Trailers) > 0 {
+		buf.WriteString("\n\n")
+		writeHeaders(buf, r.Trailers)
+	}
+	return buf.Bytes(), err
 }
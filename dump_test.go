@@ -64,3 +64,22 @@ func TestDecolorization(t *testing.T) {
 		assert.Equal(t, text, string(nocolor))
 	}
 }
+
+func TestDumpHTTP2(t *testing.T) {
+	r := &Request{
+		Method:        "GET",
+		URL:           "/h2",
+		Proto:         "HTTP/2.0",
+		StreamID:      3,
+		PseudoHeaders: []HeaderField{{":method", "GET"}, {":scheme", "https"}, {":path", "/h2"}, {":authority", "localhost"}},
+		Headers:       []HeaderField{{"User-Agent", "test"}, {"Accept", "*/*"}},
+	}
+	buf, err := r.Dump()
+	require.NoError(t, err)
+	assert.Equal(t, "GET /h2 HTTP/2.0\nStream: 3\n:method: GET\n:scheme: https\n:path: /h2\n:authority: localhost\nAccept: */*\nUser-Agent: test\n", string(Decolorize(buf)))
+
+	reply := &Reply{Status: 200, Body: []byte("hi"), Size: 2, Trailers: []HeaderField{{"X-Checksum", "abc"}}}
+	buf, err = reply.Dump()
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\n\nhi\n\nX-Checksum: abc\n", string(Decolorize(buf)))
+}
@@ -0,0 +1,363 @@
+package httplab
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExportFormat is a format captured requests can be exported as.
+type ExportFormat string
+
+// ExportFormat values
+const (
+	// ExportDump is the same pretty printed text shown by the UI, without colors.
+	ExportDump ExportFormat = "dump"
+	// ExportCurl is one curl command per request.
+	ExportCurl ExportFormat = "curl"
+	// ExportRaw is the request as it was received on the wire.
+	ExportRaw ExportFormat = "raw"
+	// ExportHAR is an HTTP Archive 1.2 log including the responses sent.
+	ExportHAR ExportFormat = "har"
+)
+
+// ExportFormats lists every supported ExportFormat.
+var ExportFormats = []ExportFormat{ExportDump, ExportCurl, ExportRaw, ExportHAR}
+
+// ParseExportFormat validates a format name.
+func ParseExportFormat(name string) (ExportFormat, error) {
+	for _, f := range ExportFormats {
+		if string(f) == strings.ToLower(name) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format '%s'", name)
+}
+
+// ExportFormatFor guesses the format from a file name extension, ExportDump when there isn't a match.
+func ExportFormatFor(filename string) ExportFormat {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".har":
+		return ExportHAR
+	case ".curl", ".sh":
+		return ExportCurl
+	case ".http", ".raw":
+		return ExportRaw
+	}
+	return ExportDump
+}
+
+// Export writes reqs to w in the given format.
+func Export(w io.Writer, format ExportFormat, reqs []*Request) error {
+	if format == ExportHAR {
+		return WriteHAR(w, reqs)
+	}
+
+	for i, r := range reqs {
+		var buf []byte
+		switch format {
+		case ExportCurl:
+			buf = []byte(r.Curl() + "\n")
+		case ExportRaw:
+			buf = r.Raw()
+		case ExportDump:
+			dump, err := r.Dump()
+			if err != nil {
+				return err
+			}
+			buf = append(Decolorize(dump), '\n')
+		default:
+			return fmt.Errorf("unknown export format '%s'", format)
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AbsoluteURL returns the URL the request was sent to, including scheme and host.
+func (r *Request) AbsoluteURL() string {
+	if u, err := url.Parse(r.URL); err == nil && u.IsAbs() {
+		return r.URL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + valueOrDefault(r.Host, "localhost") + r.URL
+}
+
+// Curl returns a curl command reproducing the request, one option per line.
+func (r *Request) Curl() string {
+	cmd := "curl"
+	switch {
+	case r.Method == "HEAD":
+		cmd += " -I"
+	case len(r.Body) == 0 && r.Method != "GET", len(r.Body) > 0 && r.Method != "POST":
+		cmd += " -X " + shellQuote(r.Method)
+	}
+	lines := []string{cmd + " " + shellQuote(r.AbsoluteURL())}
+
+	for _, h := range r.Headers {
+		// curl computes these on its own
+		if h.Name == "Content-Length" || h.Name == "Host" {
+			continue
+		}
+		lines = append(lines, "-H "+shellQuote(h.Name+": "+h.Value))
+	}
+
+	if len(r.Body) > 0 {
+		lines = append(lines, "--data-binary "+shellQuote(string(r.Body)))
+	}
+
+	return strings.Join(lines, " \\\n  ")
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,?&", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Raw returns the request in HTTP/1.1 wire format, with headers in the captured order.
+// It's HTTP/1.1 whatever protocol it was received on, the Host and Content-Length headers
+// are those of the captured request and body, which is written decoded.
+func (r *Request) Raw() []byte {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "%s %s HTTP/1.1\r\n", r.Method, r.URL)
+	if r.Host != "" {
+		fmt.Fprintf(buf, "Host: %s\r\n", r.Host)
+	}
+
+	var length bool
+	for _, h := range r.Headers {
+		switch http.CanonicalHeaderKey(h.Name) {
+		case "Host", "Transfer-Encoding":
+		case "Content-Length":
+			fmt.Fprintf(buf, "Content-Length: %d\r\n", len(r.Body))
+			length = true
+		default:
+			fmt.Fprintf(buf, "%s: %s\r\n", h.Name, h.Value)
+		}
+	}
+	if !length && len(r.Body) > 0 {
+		fmt.Fprintf(buf, "Content-Length: %d\r\n", len(r.Body))
+	}
+	buf.WriteString("\r\n")
+	buf.Write(r.Body)
+	return buf.Bytes()
+}
+
+// The HAR 1.2 types, see http://www.softwareishard.com/blog/har-12-spec/
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// WriteHAR writes reqs, along with their replies, as an HTTP Archive 1.2 log.
+func WriteHAR(w io.Writer, reqs []*Request) error {
+	doc := har{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "httplab", Version: Version},
+		Entries: make([]harEntry, 0, len(reqs)),
+	}}
+	for _, r := range reqs {
+		doc.Log.Entries = append(doc.Log.Entries, r.harEntry())
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func (r *Request) harEntry() harEntry {
+	hdr := r.Header()
+	e := harEntry{
+		StartedDateTime: r.ReceivedAt.Format("2006-01-02T15:04:05.000Z07:00"),
+		Request: harRequest{
+			Method:      r.Method,
+			URL:         r.AbsoluteURL(),
+			HTTPVersion: r.Proto,
+			Cookies:     harCookies((&http.Request{Header: hdr}).Cookies()),
+			Headers:     harHeaders(r.Headers),
+			HeadersSize: -1,
+			BodySize:    len(r.Body),
+		},
+		Response: harResponse{
+			HTTPVersion: r.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+		},
+		Comment: r.Route,
+	}
+
+	query := ""
+	if i := strings.IndexByte(r.URL, '?'); i >= 0 {
+		query = r.URL[i+1:]
+	}
+	e.Request.QueryString = harQuery(query)
+
+	if len(r.Body) > 0 {
+		e.Request.PostData = &harPostData{MimeType: hdr.Get("Content-Type"), Text: string(r.Body)}
+	}
+
+	reply := r.Reply
+	if reply == nil {
+		// The response was never sent
+		e.Response.BodySize = -1
+		return e
+	}
+
+	rhdr := make(http.Header)
+	for _, h := range reply.Headers {
+		rhdr.Add(h.Name, h.Value)
+	}
+
+	e.Response.Status = reply.Status
+	e.Response.StatusText = http.StatusText(reply.Status)
+	e.Response.Cookies = harCookies((&http.Response{Header: rhdr}).Cookies())
+	e.Response.Headers = harHeaders(reply.Headers)
+	e.Response.RedirectURL = rhdr.Get("Location")
+	e.Response.BodySize = reply.Size
+	e.Response.Content = harContent{Size: reply.Size, MimeType: rhdr.Get("Content-Type")}
+	if utf8.Valid(reply.Body) {
+		e.Response.Content.Text = string(reply.Body)
+	} else {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString(reply.Body)
+		e.Response.Content.Encoding = "base64"
+	}
+
+	e.Timings.Wait = milliseconds(reply.StartedAt.Sub(r.ReceivedAt).Seconds())
+	e.Timings.Receive = milliseconds(reply.SentAt.Sub(reply.StartedAt).Seconds())
+	e.Time = e.Timings.Send + e.Timings.Wait + e.Timings.Receive
+	return e
+}
+
+func milliseconds(seconds float64) float64 {
+	if seconds < 0 {
+		return 0
+	}
+	return float64(int64(seconds*1e6)) / 1e3
+}
+
+func harHeaders(fields []HeaderField) []harNameValue {
+	hs := make([]harNameValue, len(fields))
+	for i, h := range fields {
+		hs[i] = harNameValue{h.Name, h.Value}
+	}
+	return hs
+}
+
+// harQuery parses a query string keeping the parameters order.
+func harQuery(query string) []harNameValue {
+	qs := []harNameValue{}
+	for _, param := range strings.Split(query, "&") {
+		if param == "" {
+			continue
+		}
+		kv := strings.SplitN(param, "=", 2)
+		k, _ := url.QueryUnescape(kv[0])
+		v := ""
+		if len(kv) == 2 {
+			v, _ = url.QueryUnescape(kv[1])
+		}
+		qs = append(qs, harNameValue{k, v})
+	}
+	return qs
+}
+
+func harCookies(cookies []*http.Cookie) []harNameValue {
+	cs := make([]harNameValue, len(cookies))
+	for i, c := range cookies {
+		cs[i] = harNameValue{c.Name, c.Value}
+	}
+	return cs
+}
@@ -0,0 +1,152 @@
+package httplab
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExportRequest() *Request {
+	return &Request{
+		Method: "POST",
+		URL:    "/users?b=2&a=1",
+		Host:   "example.com",
+		Proto:  "HTTP/1.1",
+		Headers: []HeaderField{
+			{"Content-Type", "application/json"},
+			{"X-Quote", "it's"},
+			{"Cookie", "session=abc"},
+			{"Content-Length", "12"},
+		},
+		Body:       []byte(`{"id": 1234}`),
+		ReceivedAt: time.Date(2017, 6, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestExportFormatFor(t *testing.T) {
+	for name, format := range map[string]ExportFormat{
+		"reqs.har":  ExportHAR,
+		"reqs.HAR":  ExportHAR,
+		"reqs.sh":   ExportCurl,
+		"reqs.curl": ExportCurl,
+		"reqs.http": ExportRaw,
+		"reqs.raw":  ExportRaw,
+		"reqs.txt":  ExportDump,
+		"":          ExportDump,
+	} {
+		assert.Equal(t, format, ExportFormatFor(name), name)
+	}
+
+	_, err := ParseExportFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestRequestCurl(t *testing.T) {
+	r := newExportRequest()
+	assert.Equal(t, `curl http://example.com/users?b=2&a=1 \
+  -H 'Content-Type: application/json' \
+  -H 'X-Quote: it'\''s' \
+  -H 'Cookie: session=abc' \
+  --data-binary '{"id": 1234}'`, r.Curl())
+
+	t.Run("Method", func(t *testing.T) {
+		r := &Request{Method: "DELETE", URL: "/x", Host: "localhost:10080", TLS: &TLSInfo{}}
+		assert.Equal(t, "curl -X DELETE https://localhost:10080/x", r.Curl())
+	})
+}
+
+func TestRequestRaw(t *testing.T) {
+	r := newExportRequest()
+	assert.Equal(t, "POST /users?b=2&a=1 HTTP/1.1\r\n"+
+		"Host: example.com\r\n"+
+		"Content-Type: application/json\r\n"+
+		"X-Quote: it's\r\n"+
+		"Cookie: session=abc\r\n"+
+		"Content-Length: 12\r\n"+
+		"\r\n"+
+		`{"id": 1234}`, string(r.Raw()))
+
+	t.Run("HTTP/2", func(t *testing.T) {
+		r := &Request{
+			Method:  "PUT",
+			URL:     "/upload",
+			Host:    "example.com",
+			Proto:   "HTTP/2.0",
+			Headers: []HeaderField{{"Transfer-Encoding", "chunked"}, {"X-Id", "1"}},
+			Body:    []byte("data"),
+		}
+		assert.Equal(t, "PUT /upload HTTP/1.1\r\n"+
+			"Host: example.com\r\n"+
+			"X-Id: 1\r\n"+
+			"Content-Length: 4\r\n"+
+			"\r\n"+
+			"data", string(r.Raw()))
+	})
+}
+
+func TestWriteHAR(t *testing.T) {
+	r := newExportRequest()
+	r.Route = "POST /users"
+	r.Reply = &Reply{
+		Status:    201,
+		Headers:   []HeaderField{{"Content-Type", "application/octet-stream"}},
+		Body:      []byte{0xff, 0x00},
+		Size:      2,
+		StartedAt: r.ReceivedAt.Add(250 * time.Millisecond),
+		SentAt:    r.ReceivedAt.Add(300 * time.Millisecond),
+	}
+	pending := &Request{Method: "GET", URL: "/", Proto: "HTTP/1.1", ReceivedAt: r.ReceivedAt}
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, Export(buf, ExportHAR, []*Request{r, pending}))
+
+	var doc har
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "1.2", doc.Log.Version)
+	assert.Equal(t, Version, doc.Log.Creator.Version)
+	require.Len(t, doc.Log.Entries, 2)
+
+	e := doc.Log.Entries[0]
+	assert.Equal(t, "2017-06-01T10:00:00.000Z", e.StartedDateTime)
+	assert.Equal(t, "http://example.com/users?b=2&a=1", e.Request.URL)
+	assert.Equal(t, []harNameValue{{"b", "2"}, {"a", "1"}}, e.Request.QueryString)
+	assert.Equal(t, []harNameValue{{"session", "abc"}}, e.Request.Cookies)
+	assert.Equal(t, `{"id": 1234}`, e.Request.PostData.Text)
+	assert.Equal(t, "application/json", e.Request.PostData.MimeType)
+	assert.Equal(t, "POST /users", e.Comment)
+
+	assert.Equal(t, 201, e.Response.Status)
+	assert.Equal(t, "Created", e.Response.StatusText)
+	assert.Equal(t, "base64", e.Response.Content.Encoding)
+	assert.Equal(t, "/wA=", e.Response.Content.Text)
+	assert.Equal(t, 250.0, e.Timings.Wait)
+	assert.Equal(t, 50.0, e.Timings.Receive)
+	assert.Equal(t, 300.0, e.Time)
+
+	e = doc.Log.Entries[1]
+	assert.Equal(t, 0, e.Response.Status)
+	assert.Equal(t, int64(-1), e.Response.BodySize)
+	assert.NotNil(t, e.Request.QueryString)
+}
+
+func TestReplyRecorder(t *testing.T) {
+	resp := &Response{Status: 404, Headers: http.Header{"X-Foo": {"bar"}}, Body: Body{Mode: BodyInput, Input: []byte("not here")}}
+
+	w := httptest.NewRecorder()
+	rec := NewReplyRecorder(w)
+	require.NoError(t, resp.Serve(rec, httptest.NewRequest("GET", "/", nil)))
+
+	reply := rec.Reply()
+	assert.Equal(t, 404, reply.Status)
+	assert.Contains(t, reply.Headers, HeaderField{"X-Foo", "bar"})
+	assert.Equal(t, "not here", string(reply.Body))
+	assert.Equal(t, int64(8), reply.Size)
+	assert.False(t, reply.SentAt.Before(reply.StartedAt))
+	assert.Equal(t, "not here", w.Body.String())
+}
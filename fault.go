@@ -0,0 +1,224 @@
+package httplab
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// FaultType is the way a Fault breaks a response.
+type FaultType string
+
+// FaultType values
+const (
+	// FaultClose hijacks the connection and closes it before sending any headers.
+	FaultClose FaultType = "close"
+	// FaultReset sends the headers and half of the body, then resets the connection.
+	FaultReset FaultType = "reset"
+	// FaultShortBody advertises a Content-Length larger than the body, then closes the connection.
+	FaultShortBody FaultType = "short-body"
+	// FaultMalformed writes a raw, malformed status line followed by the headers and the body.
+	FaultMalformed FaultType = "malformed"
+	// FaultHang never answers, until the client goes away.
+	FaultHang FaultType = "hang"
+)
+
+// shortBodyMissing is the amount of bytes FaultShortBody advertises on top of the body size.
+const shortBodyMissing = 1024
+
+// defaultMalformedStatusLine is written by FaultMalformed when Fault.StatusLine isn't set.
+const defaultMalformedStatusLine = "HTTP/1.1 OK"
+
+// Fault breaks a response on purpose, to check how clients cope with misbehaving servers.
+//
+// In the config:
+//
+//	"Fault": {"Type": "reset", "Probability": 0.2}
+type Fault struct {
+	Type FaultType
+	// Probability of injecting the fault on each response, from 0 to 1. It's 1 when missing from the config.
+	Probability float64
+	// StatusLine is written by FaultMalformed, "HTTP/1.1 OK" by default.
+	StatusLine string `json:",omitempty"`
+}
+
+// ParseFaultType validates a FaultType name.
+func ParseFaultType(name string) (FaultType, error) {
+	switch t := FaultType(name); t {
+	case FaultClose, FaultReset, FaultShortBody, FaultMalformed, FaultHang:
+		return t, nil
+	}
+	return "", fmt.Errorf("Fault: unknown type '%s', expected close, reset, short-body, malformed or hang", name)
+}
+
+// UnmarshalJSON validates the fault type and defaults Probability to 1.
+func (f *Fault) UnmarshalJSON(data []byte) error {
+	type alias Fault
+	v := alias{Probability: 1}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if _, err := ParseFaultType(string(v.Type)); err != nil {
+		return err
+	}
+	if v.Probability < 0 || v.Probability > 1 {
+		return fmt.Errorf("Fault: Probability must be between 0 and 1, got %v", v.Probability)
+	}
+
+	*f = Fault(v)
+	return nil
+}
+
+// Triggers tells whether the fault has to be injected into the next response.
+func (f *Fault) Triggers() bool {
+	return f.Probability >= 1 || rand.Float64() < f.Probability
+}
+
+// Inject breaks the response r to req as described by the fault.
+// It returns an error telling the fault has been injected, or why it couldn't be.
+func (f *Fault) Inject(w http.ResponseWriter, req *http.Request, r *Response) error {
+	var err error
+	switch f.Type {
+	case FaultClose:
+		err = f.close(w)
+	case FaultReset:
+		err = f.reset(w, r)
+	case FaultShortBody:
+		err = f.shortBody(w, r)
+	case FaultMalformed:
+		err = f.malformed(w, r)
+	case FaultHang:
+		<-req.Context().Done()
+	default:
+		_, err = ParseFaultType(string(f.Type))
+	}
+
+	if err != nil {
+		return fmt.Errorf("Fault %s: %v", f.Type, err)
+	}
+	return fmt.Errorf("Fault injected: %s", f.Type)
+}
+
+func (f *Fault) close(w http.ResponseWriter) error {
+	conn, _, err := hijack(w)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (f *Fault) reset(w http.ResponseWriter, r *Response) error {
+	body, size, err := r.Body.Reader()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(r.Status)
+	if _, err := io.CopyN(w, body, size/2); err != nil {
+		return err
+	}
+	flush(w)
+
+	conn, _, err := hijack(w)
+	if err != nil {
+		return err
+	}
+	return resetConn(conn)
+}
+
+func (f *Fault) shortBody(w http.ResponseWriter, r *Response) error {
+	body, size, err := r.Body.Reader()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Length", strconv.FormatInt(size+shortBodyMissing, 10))
+	w.WriteHeader(r.Status)
+	if _, err := io.Copy(w, body); err != nil {
+		return err
+	}
+	flush(w)
+
+	conn, _, err := hijack(w)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (f *Fault) malformed(w http.ResponseWriter, r *Response) error {
+	body, size, err := r.Body.Reader()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	conn, buf, err := hijack(w)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	statusLine := f.StatusLine
+	if statusLine == "" {
+		statusLine = defaultMalformedStatusLine
+	}
+
+	hdr := w.Header().Clone()
+	hdr.Set("Content-Length", strconv.FormatInt(size, 10))
+	hdr.Set("Connection", "close")
+
+	fmt.Fprintf(buf, "%s\r\n", statusLine)
+	if err := hdr.Write(buf); err != nil {
+		return err
+	}
+	buf.WriteString("\r\n")
+	if _, err := io.Copy(buf, body); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// flush sends whatever has been written into w so far.
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// hijack takes over the connection of w.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the connection can't be hijacked")
+	}
+	return hj.Hijack()
+}
+
+// resetConn closes c sending a TCP RST instead of a FIN, when it's possible.
+func resetConn(c net.Conn) error {
+	for {
+		if tcp, ok := c.(*net.TCPConn); ok {
+			if err := tcp.SetLinger(0); err != nil {
+				return err
+			}
+			break
+		}
+
+		wrapper, ok := c.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+		c = wrapper.NetConn()
+	}
+	return c.Close()
+}
@@ -0,0 +1,135 @@
+package httplab
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func faultServer(t *testing.T, resp *Response) (*httptest.Server, chan error) {
+	errs := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		errs <- resp.Serve(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, errs
+}
+
+func faultyResponse(fault Fault) *Response {
+	return &Response{
+		Status:  200,
+		Headers: http.Header{"X-Foo": []string{"bar"}},
+		Body:    Body{Mode: BodyInput, Input: []byte(strings.Repeat("x", 64))},
+		Fault:   &fault,
+	}
+}
+
+func TestFaultClose(t *testing.T) {
+	srv, errs := faultServer(t, faultyResponse(Fault{Type: FaultClose, Probability: 1}))
+
+	_, err := http.Get(srv.URL)
+	assert.Error(t, err)
+	assert.EqualError(t, <-errs, "Fault injected: close")
+}
+
+func TestFaultReset(t *testing.T) {
+	srv, errs := faultServer(t, faultyResponse(Fault{Type: FaultReset, Probability: 1}))
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, "bar", res.Header.Get("X-Foo"))
+	assert.Equal(t, int64(64), res.ContentLength)
+	body, err := io.ReadAll(res.Body)
+	assert.Error(t, err)
+	assert.True(t, len(body) <= 32)
+	assert.EqualError(t, <-errs, "Fault injected: reset")
+}
+
+func TestFaultShortBody(t *testing.T) {
+	srv, errs := faultServer(t, faultyResponse(Fault{Type: FaultShortBody, Probability: 1}))
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, int64(64+shortBodyMissing), res.ContentLength)
+	body, err := io.ReadAll(res.Body)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, strings.Repeat("x", 64), string(body))
+	assert.EqualError(t, <-errs, "Fault injected: short-body")
+}
+
+func TestFaultMalformed(t *testing.T) {
+	srv, errs := faultServer(t, faultyResponse(Fault{Type: FaultMalformed, Probability: 1, StatusLine: "HTTP/1.1 2OO OK"}))
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	require.NoError(t, err)
+
+	raw, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(raw), "HTTP/1.1 2OO OK\r\n"), string(raw))
+	assert.Contains(t, string(raw), "X-Foo: bar\r\n")
+	assert.True(t, strings.HasSuffix(string(raw), "\r\n\r\n"+strings.Repeat("x", 64)))
+
+	_, err = http.ReadResponse(bufio.NewReader(strings.NewReader(string(raw))), nil)
+	assert.Error(t, err)
+	assert.EqualError(t, <-errs, "Fault injected: malformed")
+}
+
+func TestFaultHang(t *testing.T) {
+	srv, errs := faultServer(t, faultyResponse(Fault{Type: FaultHang, Probability: 1}))
+
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	_, err := client.Get(srv.URL)
+	assert.Error(t, err)
+
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "Fault injected: hang")
+	case <-time.After(time.Second):
+		t.Fatal("hanging response didn't return once the client was gone")
+	}
+}
+
+func TestFaultProbability(t *testing.T) {
+	srv, errs := faultServer(t, faultyResponse(Fault{Type: FaultClose, Probability: 0}))
+
+	for i := 0; i < 10; i++ {
+		res, err := http.Get(srv.URL)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.NoError(t, <-errs)
+	}
+}
+
+func TestFaultJSON(t *testing.T) {
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(`{"Status": 200, "Fault": {"Type": "reset"}}`), &resp))
+	require.NotNil(t, resp.Fault)
+	assert.Equal(t, Fault{Type: FaultReset, Probability: 1}, *resp.Fault)
+
+	data, err := json.Marshal(&resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Fault":{"Type":"reset","Probability":1}`)
+
+	err = json.Unmarshal([]byte(`{"Fault": {"Type": "explode"}}`), &resp)
+	assert.EqualError(t, err, "Fault: unknown type 'explode', expected close, reset, short-body, malformed or hang")
+
+	err = json.Unmarshal([]byte(`{"Fault": {"Type": "hang", "Probability": 2}}`), &resp)
+	assert.EqualError(t, err, "Fault: Probability must be between 0 and 1, got 2")
+}
@@ -0,0 +1,297 @@
+package httplab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat is a format the config file can be written in.
+type ConfigFormat string
+
+// ConfigFormat values
+const (
+	ConfigJSON ConfigFormat = "json"
+	// ConfigYAML writes multi-line bodies as block scalars.
+	ConfigYAML ConfigFormat = "yaml"
+	// ConfigTOML writes multi-line bodies as multi-line literal strings.
+	ConfigTOML ConfigFormat = "toml"
+)
+
+// ConfigFormatFor guesses the format from a file name extension, ConfigJSON when there isn't a match.
+func ConfigFormatFor(filename string) ConfigFormat {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ConfigYAML
+	case ".toml":
+		return ConfigTOML
+	}
+	return ConfigJSON
+}
+
+// ConvertConfig writes the config file at input into output, in the format of its extension, see ConfigFormatFor.
+// The files input includes aren't converted, its Include list is written as it is.
+func ConvertConfig(input, output string) error {
+	c, _, err := loadConfig(input)
+	if err != nil {
+		return err
+	}
+	_, err = writeConfig(output, c)
+	return err
+}
+
+// marshal encodes c. On YAML and TOML, JSON bodies are written as native objects when they'd be read back
+// exactly as they are, strings otherwise.
+func (f ConfigFormat) marshal(c *config) ([]byte, error) {
+	if f == ConfigJSON {
+		return json.MarshalIndent(c, "", "  ")
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree map[string]interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	tree = fromJSON(tree).(map[string]interface{})
+
+	responses, _ := tree["Responses"].(map[string]interface{})
+	for _, v := range responses {
+		resp, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// these are the defaults
+		for _, key := range []string{"Body", "File"} {
+			if resp[key] == "" {
+				delete(resp, key)
+			}
+		}
+		if headers, ok := resp["Headers"].(map[string]interface{}); ok && len(headers) == 0 {
+			delete(resp, "Headers")
+		}
+
+		if body, ok := resp["Body"].(string); ok {
+			if native, ok := f.nativeBody(body); ok {
+				resp["Body"] = native
+			}
+		}
+	}
+
+	return f.encode(tree)
+}
+
+// unmarshal decodes data into c.
+func (f ConfigFormat) unmarshal(data []byte, c *config) error {
+	if f == ConfigJSON {
+		return json.Unmarshal(data, c)
+	}
+
+	tree, err := f.decode(data)
+	if err != nil {
+		return err
+	}
+
+	responses, _ := tree["Responses"].(map[string]interface{})
+	for _, v := range responses {
+		resp, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch body := resp["Body"].(type) {
+		case map[string]interface{}, []interface{}:
+			s, err := compactJSON(body)
+			if err != nil {
+				return err
+			}
+			resp["Body"] = s
+		}
+	}
+
+	data, err = json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
+
+func (f ConfigFormat) encode(tree map[string]interface{}) ([]byte, error) {
+	switch f {
+	case ConfigYAML:
+		buf := &bytes.Buffer{}
+		enc := yaml.NewEncoder(buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(tree); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case ConfigTOML:
+		return toml.Marshal(blockStrings(tree))
+	}
+	return nil, fmt.Errorf("unknown config format '%s'", f)
+}
+
+func (f ConfigFormat) decode(data []byte) (map[string]interface{}, error) {
+	var tree interface{}
+	switch f {
+	case ConfigYAML:
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+	case ConfigTOML:
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format '%s'", f)
+	}
+
+	if tree == nil {
+		return map[string]interface{}{}, nil
+	}
+	m, ok := stringKeys(tree).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the config should be a map, got %T", tree)
+	}
+	return m, nil
+}
+
+// nativeBody returns the JSON object or array body holds, when it'd be read back as the same string.
+func (f ConfigFormat) nativeBody(body string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return nil, false
+	}
+	v = fromJSON(v)
+
+	// round-trip it, whatever the format can't hold, like nulls on TOML, makes it differ
+	data, err := f.encode(map[string]interface{}{"Body": v})
+	if err != nil {
+		return nil, false
+	}
+	back, err := f.decode(data)
+	if err != nil {
+		return nil, false
+	}
+	s, err := compactJSON(back["Body"])
+	if err != nil || s != body {
+		return nil, false
+	}
+	return v, true
+}
+
+// compactJSON encodes v in a single line, with sorted keys and without escaping HTML.
+func compactJSON(v interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// fromJSON replaces the json.Numbers of v with int64 or float64 values.
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = fromJSON(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = fromJSON(value)
+		}
+	}
+	return v
+}
+
+// stringKeys turns the maps of v into map[string]interface{}, as YAML keys can be of any type.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = stringKeys(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+	}
+	return v
+}
+
+// blockString is a multi-line string written as a TOML multi-line literal string.
+type blockString string
+
+// MarshalTOML implements toml.Marshaler.
+func (s blockString) MarshalTOML() ([]byte, error) {
+	return []byte("'''\n" + string(s) + "'''"), nil
+}
+
+// isBlockString reports whether s is a multi-line string which can be written as is, without escaping.
+func isBlockString(s string) bool {
+	if !strings.Contains(s, "\n") || strings.Contains(s, "'''") || strings.HasSuffix(s, "'") {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\n' && r != '\t' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// blockStrings replaces the multi-line strings of v with blockStrings.
+func blockStrings(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if isBlockString(v) {
+			return blockString(v)
+		}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = blockStrings(value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = blockStrings(value)
+		}
+		return s
+	}
+	return v
+}
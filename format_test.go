@@ -0,0 +1,96 @@
+package httplab
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFormatFor(t *testing.T) {
+	assert.Equal(t, ConfigJSON, ConfigFormatFor(".httplab"))
+	assert.Equal(t, ConfigJSON, ConfigFormatFor("httplab.json"))
+	assert.Equal(t, ConfigYAML, ConfigFormatFor("httplab.yaml"))
+	assert.Equal(t, ConfigYAML, ConfigFormatFor(".httplab.YML"))
+	assert.Equal(t, ConfigTOML, ConfigFormatFor("httplab.toml"))
+}
+
+func TestConfigFormats(t *testing.T) {
+	body := func(s string) Body { return Body{Mode: BodyInput, Input: []byte(s)} }
+	// every format gets its own list, as entries are saved back to the file they were saved into
+	newList := func() *ResponsesList {
+		rl := NewResponsesList()
+		rl.Add("text", &Response{Status: 200, Body: body("line 1\nline 2\n")})
+		rl.Add("object", &Response{Status: 201, Body: body(`{"created":"ok","ids":[1,2.5]}`)})
+		rl.Add("indented", &Response{Status: 200, Body: body("{\n  \"a\": 1\n}")})
+		rl.Add("unsorted", &Response{Status: 200, Body: body(`{"b":1,"a":2}`)})
+		rl.Add("null", &Response{Status: 200, Body: body(`{"a":null}`)})
+		rl.Add("html", &Response{Status: 200, Body: body(`["<b>"]`)})
+		rl.Add("template", &Response{Status: 200, Body: Body{Mode: BodyTemplate, Input: []byte("{{.Method}}\n")}})
+		rl.Routes = []*Route{{Method: "POST", Path: "/users", Response: "object"}}
+		rl.Sequences["seq"] = &Sequence{Responses: []string{"text", "object"}}
+		return rl
+	}
+
+	for format, expected := range map[string][]string{
+		"yaml": {
+			"Body: |\n      line 1\n      line 2\n",
+			"Body:\n      created: ok\n      ids:\n        - 1\n        - 2.5\n",
+			"Body: |-\n      {\n        \"a\": 1\n      }\n",
+			`Body: '{"b":1,"a":2}'`,
+			"Body:\n      a: null\n",
+			"Body:\n      - <b>\n",
+		},
+		"toml": {
+			"Body = '''\nline 1\nline 2\n'''",
+			"[Responses.object.Body]\n      created = \"ok\"\n      ids = [1, 2.5]\n",
+			`Body = "{\"a\":null}"`,
+			"Template = '''\n{{.Method}}\n'''",
+		},
+	} {
+		rl := newList()
+		path := filepath.Join(t.TempDir(), "httplab."+format)
+		require.NoError(t, rl.Save(path))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		for _, s := range expected {
+			assert.Contains(t, string(data), s, format)
+		}
+
+		loaded := NewResponsesList()
+		require.NoError(t, loaded.Load(path), format)
+
+		// nothing is lost
+		before, err := takeSnapshot(rl.config())
+		require.NoError(t, err)
+		after, err := takeSnapshot(loaded.config())
+		require.NoError(t, err)
+		assert.Equal(t, before, after, format)
+	}
+}
+
+func TestLoadYAMLWithNativeBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "httplab.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+Responses:
+  create:
+    Status: 201
+    Headers:
+      Content-Type: application/json
+    Body:
+      created: ok
+      user: {id: 1, tags: [a, b]}
+  text:
+    Body: |
+      multi
+      line
+`), 0644))
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	assert.Equal(t, `{"created":"ok","user":{"id":1,"tags":["a","b"]}}`, string(rl.Get("create").Body.Payload()))
+	assert.Equal(t, "multi\nline\n", string(rl.Get("text").Body.Payload()))
+}
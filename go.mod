@@ -1,12 +1,16 @@
 module github.com/gchaincl/httplab
 
-go 1.20
+go 1.27
 
 require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/jroimartin/gocui v0.5.0
 	github.com/rs/cors v0.0.0-20170529160756-bf64c5349c0f
 	github.com/spf13/pflag v0.0.0-20170901120850-7aff26db30c1
 	github.com/stretchr/testify v0.0.0-20170130113145-4d4bfba8f1d1
+	golang.org/x/net v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,4 +19,5 @@ require (
 	github.com/nsf/termbox-go v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
+	golang.org/x/text v0.22.0 // indirect
 )
@@ -0,0 +1,184 @@
+package httplab
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// History persists captured requests into a JSON lines file.
+// Requests are appended as they arrive and their replies once they're sent, see AppendReply.
+// Once the file reaches MaxCount requests or MaxSize bytes it is rotated,
+// the previous file is kept with a ".1" suffix.
+type History struct {
+	Path string
+	// MaxCount is the number of requests kept before rotating, 0 means no limit.
+	MaxCount int
+	// MaxSize is the size in bytes kept before rotating, 0 means no limit.
+	MaxSize int64
+
+	mu    sync.Mutex
+	count int
+	size  int64
+}
+
+// historyReply is the line appended once a request is replied, Load attaches the reply
+// to the request received at ReplyTo from RemoteAddr.
+type historyReply struct {
+	ReplyTo    time.Time
+	RemoteAddr string
+	Reply      *Reply
+}
+
+// replyPrefix starts the historyReply lines, ReplyTo being its first field.
+var replyPrefix = []byte(`{"ReplyTo":`)
+
+// NewHistory returns a History stored at path.
+func NewHistory(path string, maxCount int, maxSize int64) *History {
+	return &History{Path: ExpandPath(path), MaxCount: maxCount, MaxSize: maxSize}
+}
+
+// Load reads the requests stored in the history file, a missing file is an empty history.
+func (h *History) Load() ([]*Request, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.count, h.size = 0, 0
+
+	f, err := os.Open(h.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var reqs []*Request
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		h.size += int64(len(scanner.Bytes())) + 1
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		if bytes.HasPrefix(scanner.Bytes(), replyPrefix) {
+			var reply historyReply
+			if err := json.Unmarshal(scanner.Bytes(), &reply); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", h.Path, line, err)
+			}
+			attachReply(reqs, &reply)
+			continue
+		}
+
+		var r Request
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", h.Path, line, err)
+		}
+		reqs = append(reqs, &r)
+	}
+	h.count = len(reqs)
+
+	return reqs, scanner.Err()
+}
+
+// attachReply sets the reply on the request it belongs to, replies of requests rotated away are dropped.
+func attachReply(reqs []*Request, reply *historyReply) {
+	for i := len(reqs) - 1; i >= 0; i-- {
+		if reqs[i].ReceivedAt.Equal(reply.ReplyTo) && reqs[i].RemoteAddr == reply.RemoteAddr {
+			reqs[i].Reply = reply.Reply
+			return
+		}
+	}
+}
+
+// Append writes r at the end of the history file, rotating it first if it's full.
+// Captured requests are appended as soon as they arrive, their reply is appended later with AppendReply.
+func (h *History) Append(r *Request) error {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.full(int64(len(buf) + 1)) {
+		if err := h.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if err := h.write(buf); err != nil {
+		return err
+	}
+	h.count++
+	return nil
+}
+
+// AppendReply writes the reply of r, a request already appended, at the end of the history file.
+// The file isn't rotated for replies, so that they're kept along with their request.
+func (h *History) AppendReply(r *Request) error {
+	if r.Reply == nil {
+		return nil
+	}
+
+	buf, err := json.Marshal(historyReply{ReplyTo: r.ReceivedAt, RemoteAddr: r.RemoteAddr, Reply: r.Reply})
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.write(buf)
+}
+
+// write appends the line buf to the history file.
+func (h *History) write(buf []byte) error {
+	f, err := os.OpenFile(h.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		return err
+	}
+	h.size += int64(len(buf)) + 1
+	return nil
+}
+
+func (h *History) full(next int64) bool {
+	if h.count == 0 {
+		return false
+	}
+	if h.MaxCount > 0 && h.count >= h.MaxCount {
+		return true
+	}
+	return h.MaxSize > 0 && h.size+next > h.MaxSize
+}
+
+func (h *History) rotate() error {
+	if err := os.Rename(h.Path, h.Path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	h.count, h.size = 0, 0
+	return nil
+}
+
+// Truncate removes every request from the history file.
+func (h *History) Truncate() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.Truncate(h.Path, 0); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	h.count, h.size = 0, 0
+	return nil
+}
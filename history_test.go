@@ -0,0 +1,118 @@
+package httplab
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h := NewHistory(path, 0, 0)
+
+	reqs, err := h.Load()
+	require.NoError(t, err)
+	assert.Empty(t, reqs)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, h.Append(&Request{
+			Method:  "POST",
+			URL:     fmt.Sprintf("/%d", i),
+			Headers: []HeaderField{{"Content-Type", "application/octet-stream"}},
+			Body:    []byte{0, 1, 2},
+		}))
+	}
+
+	reqs, err = NewHistory(path, 0, 0).Load()
+	require.NoError(t, err)
+	require.Len(t, reqs, 3)
+	for i, r := range reqs {
+		assert.Equal(t, fmt.Sprintf("/%d", i), r.URL)
+		assert.Equal(t, []byte{0, 1, 2}, r.Body)
+		assert.Equal(t, "application/octet-stream", r.Header().Get("Content-Type"))
+	}
+
+	t.Run("Truncate()", func(t *testing.T) {
+		require.NoError(t, h.Truncate())
+		reqs, err := h.Load()
+		require.NoError(t, err)
+		assert.Empty(t, reqs)
+	})
+}
+
+func TestHistoryAppendReply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	h := NewHistory(path, 0, 0)
+
+	received := time.Now()
+	first := &Request{URL: "/first", RemoteAddr: "127.0.0.1:1234", ReceivedAt: received}
+	second := &Request{URL: "/second", RemoteAddr: "127.0.0.1:1235", ReceivedAt: received}
+	require.NoError(t, h.Append(first))
+	require.NoError(t, h.Append(second))
+
+	// the requests are kept even if they're never replied
+	reqs, err := h.Load()
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+	assert.Nil(t, reqs[0].Reply)
+
+	first.Reply = &Reply{Status: 201}
+	require.NoError(t, h.AppendReply(first))
+
+	reqs, err = NewHistory(path, 0, 0).Load()
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+	require.NotNil(t, reqs[0].Reply)
+	assert.Equal(t, 201, reqs[0].Reply.Status)
+	assert.Nil(t, reqs[1].Reply)
+}
+
+func TestHistoryRotation(t *testing.T) {
+	t.Run("by count", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		h := NewHistory(path, 2, 0)
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, h.Append(&Request{URL: fmt.Sprintf("/%d", i)}))
+		}
+
+		reqs, err := h.Load()
+		require.NoError(t, err)
+		require.Len(t, reqs, 1)
+		assert.Equal(t, "/4", reqs[0].URL)
+
+		reqs, err = NewHistory(path+".1", 0, 0).Load()
+		require.NoError(t, err)
+		require.Len(t, reqs, 2)
+		assert.Equal(t, "/2", reqs[0].URL)
+	})
+
+	t.Run("by size", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		h := NewHistory(path, 0, 1)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, h.Append(&Request{URL: fmt.Sprintf("/%d", i)}))
+		}
+
+		// each request exceeds the size, so only the last one is kept
+		reqs, err := h.Load()
+		require.NoError(t, err)
+		require.Len(t, reqs, 1)
+		assert.Equal(t, "/2", reqs[0].URL)
+	})
+}
+
+func TestHistoryInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{}\nnot json\n"), 0644))
+
+	_, err := NewHistory(path, 0, 0).Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+":2:")
+}
@@ -0,0 +1,147 @@
+package httplab
+
+import (
+	"encoding/binary"
+	"net/http"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+// http2Preface starts every HTTP/2 connection, right after the upgrade when it's negotiated with Upgrade: h2c.
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// maxHeaderBlocks is the amount of decoded header blocks kept per connection until their request claims them.
+const maxHeaderBlocks = 100
+
+// HTTP/2 frame types and flags needed to decode the header blocks, see RFC 7540 section 6.
+const (
+	frameHeaders      = 0x1
+	frameContinuation = 0x9
+
+	flagEndHeaders = 0x4
+	flagPadded     = 0x8
+	flagPriority   = 0x20
+)
+
+// headerBlock is the header block opening an HTTP/2 stream, with its fields in the order they were received.
+type headerBlock struct {
+	streamID uint32
+	fields   []hpack.HeaderField
+}
+
+// http2Frames decodes the header blocks sent by the client on an HTTP/2 connection.
+type http2Frames struct {
+	// pending holds the bytes of the frame being received.
+	pending  []byte
+	decoder  *hpack.Decoder
+	streamID uint32
+	fragment []byte
+	blocks   []headerBlock
+	// broken is set when the frames can't be followed anymore.
+	broken bool
+}
+
+func newHTTP2Frames() *http2Frames {
+	return &http2Frames{decoder: hpack.NewDecoder(4096, nil)}
+}
+
+// write feeds the frames received, as they arrive.
+func (h *http2Frames) write(p []byte) {
+	if h.broken {
+		return
+	}
+
+	h.pending = append(h.pending, p...)
+	for len(h.pending) >= 9 {
+		length := int(h.pending[0])<<16 | int(h.pending[1])<<8 | int(h.pending[2])
+		if len(h.pending) < 9+length {
+			return
+		}
+
+		kind, flags := h.pending[3], h.pending[4]
+		streamID := binary.BigEndian.Uint32(h.pending[5:9]) & (1<<31 - 1)
+		payload := h.pending[9 : 9+length]
+
+		switch kind {
+		case frameHeaders:
+			if flags&flagPadded != 0 {
+				if len(payload) < 1 || int(payload[0]) >= len(payload) {
+					h.broken = true
+					return
+				}
+				payload = payload[1 : len(payload)-int(payload[0])]
+			}
+			if flags&flagPriority != 0 {
+				if len(payload) < 5 {
+					h.broken = true
+					return
+				}
+				payload = payload[5:]
+			}
+			h.streamID = streamID
+			h.fragment = append([]byte(nil), payload...)
+		case frameContinuation:
+			h.fragment = append(h.fragment, payload...)
+		}
+
+		if (kind == frameHeaders || kind == frameContinuation) && flags&flagEndHeaders != 0 {
+			h.decode()
+		}
+		h.pending = h.pending[9+length:]
+	}
+}
+
+// decode decodes the header block just received, keeping it if it opens a request.
+func (h *http2Frames) decode() {
+	fields, err := h.decoder.DecodeFull(h.fragment)
+	h.fragment = nil
+	if err != nil {
+		h.broken = true
+		return
+	}
+
+	// trailers don't have pseudo-headers
+	if len(fields) == 0 || !fields[0].IsPseudo() {
+		return
+	}
+
+	h.blocks = append(h.blocks, headerBlock{h.streamID, fields})
+	if len(h.blocks) > maxHeaderBlocks {
+		h.blocks = h.blocks[1:]
+	}
+}
+
+// claim returns the header block of req, removing it, nil if it isn't known.
+func (h *http2Frames) claim(req *http.Request) *headerBlock {
+	for i, block := range h.blocks {
+		var method, path string
+		for _, f := range block.fields {
+			switch f.Name {
+			case ":method":
+				method = f.Value
+			case ":path":
+				path = f.Value
+			}
+		}
+
+		if method == req.Method && path == req.RequestURI {
+			h.blocks = append(h.blocks[:i], h.blocks[i+1:]...)
+			return &block
+		}
+	}
+	return nil
+}
+
+// pseudoHeaders rebuilds the pseudo-headers of req, when the ones received aren't known.
+func pseudoHeaders(req *http.Request, secure bool) []HeaderField {
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	return []HeaderField{
+		{":method", req.Method},
+		{":scheme", scheme},
+		{":authority", req.Host},
+		{":path", req.RequestURI},
+	}
+}
@@ -0,0 +1,214 @@
+package httplab
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/net/http2/hpack"
+)
+
+func TestHTTP2Frames(t *testing.T) {
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	for _, f := range []hpack.HeaderField{
+		{Name: ":method", Value: "POST"},
+		{Name: ":path", Value: "/split"},
+		{Name: "b", Value: "1"},
+		{Name: "a", Value: "2"},
+	} {
+		require.NoError(t, enc.WriteField(f))
+	}
+
+	var buf bytes.Buffer
+	fr := http2.NewFramer(&buf, nil)
+	require.NoError(t, fr.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      5,
+		BlockFragment: block.Bytes()[:3],
+		PadLength:     4,
+		Priority:      http2.PriorityParam{Weight: 10},
+	}))
+	require.NoError(t, fr.WriteContinuation(5, true, block.Bytes()[3:]))
+
+	h := newHTTP2Frames()
+	// frames may arrive split across reads
+	data := buf.Bytes()
+	h.write(data[:7])
+	h.write(data[7:])
+
+	assert.Nil(t, h.claim(&http.Request{Method: "GET", RequestURI: "/split"}))
+	b := h.claim(&http.Request{Method: "POST", RequestURI: "/split"})
+	require.NotNil(t, b)
+	assert.Equal(t, uint32(5), b.streamID)
+	assert.Len(t, b.fields, 4)
+	assert.Empty(t, h.blocks)
+}
+
+func TestHTTP2(t *testing.T) {
+	requests := make(chan *Request, 2)
+	replies := make(chan *Reply, 1)
+	resp := &Response{
+		Status:   200,
+		Headers:  http.Header{},
+		Body:     Body{Mode: BodyInput, Input: []byte("hello")},
+		Trailers: http.Header{"X-Checksum": []string{"abc"}},
+		Push:     []string{"/style.css"},
+	}
+
+	srv := httptest.NewUnstartedServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r, err := NewRequest(req)
+		require.NoError(t, err)
+		requests <- r
+		if req.URL.Path == "/style.css" {
+			w.Write([]byte("pushed"))
+			return
+		}
+
+		rec := NewReplyRecorder(w)
+		assert.NoError(t, resp.Write(rec))
+		replies <- rec.Reply()
+	}), &http2.Server{}))
+	srv.Listener = NewListener(srv.Listener)
+	srv.Config.ConnContext = ConnContext
+	srv.Start()
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(http2.ClientPreface))
+	require.NoError(t, err)
+	dec := hpack.NewDecoder(4096, nil)
+	fr := http2.NewFramer(conn, conn)
+	fr.ReadMetaHeaders = dec
+	require.NoError(t, fr.WriteSettings())
+
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	for _, f := range []hpack.HeaderField{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":path", Value: "/h2?x=1"},
+		{Name: ":authority", Value: "localhost"},
+		{Name: "z-last", Value: "1"},
+		{Name: "a-first", Value: "2"},
+	} {
+		require.NoError(t, enc.WriteField(f))
+	}
+	require.NoError(t, fr.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: block.Bytes(),
+		EndStream:     true,
+		EndHeaders:    true,
+	}))
+
+	var promised []hpack.HeaderField
+	var headers, trailers []hpack.HeaderField
+	var body []byte
+	for done := false; !done; {
+		f, err := fr.ReadFrame()
+		require.NoError(t, err)
+
+		switch f := f.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				require.NoError(t, fr.WriteSettingsAck())
+			}
+		case *http2.PushPromiseFrame:
+			promised, err = dec.DecodeFull(f.HeaderBlockFragment())
+			require.NoError(t, err)
+		case *http2.MetaHeadersFrame:
+			if f.StreamID == 1 {
+				if headers == nil {
+					headers = f.Fields
+				} else {
+					trailers = f.Fields
+				}
+				done = f.StreamEnded()
+			}
+		case *http2.DataFrame:
+			if f.StreamID == 1 {
+				body = append(body, f.Data()...)
+				done = f.StreamEnded()
+			}
+		}
+	}
+
+	assert.Contains(t, promised, hpack.HeaderField{Name: ":path", Value: "/style.css"})
+	assert.Contains(t, headers, hpack.HeaderField{Name: ":status", Value: "200"})
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, []hpack.HeaderField{{Name: "x-checksum", Value: "abc"}}, trailers)
+
+	r := <-requests
+	assert.Equal(t, "HTTP/2.0", r.Proto)
+	assert.Equal(t, uint32(1), r.StreamID)
+	assert.Equal(t, []HeaderField{
+		{":method", "GET"},
+		{":scheme", "http"},
+		{":path", "/h2?x=1"},
+		{":authority", "localhost"},
+	}, r.PseudoHeaders)
+	assert.Equal(t, []HeaderField{{"Z-Last", "1"}, {"A-First", "2"}}, r.Headers)
+
+	// pushed requests have no header block of their own
+	r = <-requests
+	assert.Equal(t, "/style.css", r.URL)
+	assert.Equal(t, uint32(0), r.StreamID)
+	assert.Equal(t, ":method", r.PseudoHeaders[0].Name)
+
+	reply := <-replies
+	assert.Equal(t, []HeaderField{{"X-Checksum", "abc"}}, reply.Trailers)
+}
+
+func TestResponseTrailersJSON(t *testing.T) {
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"Status": 200,
+		"Trailers": {"X-Checksum": "abc"},
+		"Push": ["/app.js"]
+	}`), &resp))
+	assert.Equal(t, http.Header{"X-Checksum": []string{"abc"}}, resp.Trailers)
+	assert.Equal(t, []string{"/app.js"}, resp.Push)
+
+	data, err := json.Marshal(&resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Trailers":{"X-Checksum":"abc"}`)
+	assert.Contains(t, string(data), `"Push":["/app.js"]`)
+}
+
+func TestResponseTrailersHTTP1(t *testing.T) {
+	resp := &Response{
+		Status:   200,
+		Headers:  http.Header{},
+		Body:     Body{Mode: BodyInput, Input: []byte("hello")},
+		Trailers: http.Header{"X-Checksum": []string{"abc"}},
+		Push:     []string{"/ignored"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.NoError(t, resp.Write(w))
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, "abc", res.Trailer.Get("X-Checksum"))
+
+	t.Run("declared by hand", func(t *testing.T) {
+		resp := &Response{Status: 200, Headers: http.Header{"Trailer": {"X-Checksum"}}, Body: Body{Mode: BodyInput}}
+		rec := httptest.NewRecorder()
+		require.NoError(t, resp.Write(rec))
+		assert.Equal(t, "X-Checksum", rec.Header().Get("Trailer"))
+	})
+}
@@ -0,0 +1,163 @@
+// Package httplabtest provides an HTTP server for Go tests, serving httplab responses
+// and recording every request it receives, much like net/http/httptest.
+package httplabtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	"github.com/gchaincl/httplab"
+)
+
+// Server is an HTTP server listening on a system-chosen port on the local loopback interface.
+// Requests are served by the first matching route of its responses list,
+// or by its default response if none does.
+type Server struct {
+	*httptest.Server
+	// Brokers serve the SSE and WebSocket responses, events and frames can be broadcast to their clients.
+	Brokers *httplab.Brokers
+
+	mu        sync.Mutex
+	resp      *httplab.Response
+	responses *httplab.ResponsesList
+	requests  []*httplab.Request
+	waited    int
+	arrived   chan struct{}
+}
+
+// NewServer starts and returns a new Server answering every request with resp.
+// The caller should call Close when finished, to shut it down.
+func NewServer(resp *httplab.Response) *Server {
+	s := &Server{
+		resp:      resp,
+		responses: httplab.NewResponsesList(),
+		arrived:   make(chan struct{}),
+		Brokers:   httplab.NewBrokers(),
+	}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	s.Listener = httplab.NewListener(s.Listener)
+	s.Config.BaseContext = s.Brokers.BaseContext
+	s.Config.ConnContext = httplab.ConnContext
+	s.Start()
+	return s
+}
+
+// NewServerFromFile starts and returns a new Server serving the routes of the responses list stored at path.
+// Requests not matching any route are answered with 404 Not Found.
+// The caller should call Close when finished, to shut it down.
+func NewServerFromFile(path string) (*Server, error) {
+	// Load creates the missing files, a test has to be given an existing one
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	rl := httplab.NewResponsesList()
+	if err := rl.Load(path); err != nil {
+		return nil, err
+	}
+
+	resp, err := httplab.NewResponse("404", "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewServer(resp)
+	s.SetResponses(rl)
+	return s, nil
+}
+
+// Close ends the SSE streams and the WebSocket connections, and shuts down the server.
+// It blocks until all outstanding requests on it have completed.
+func (s *Server) Close() {
+	s.Brokers.Close()
+	s.Server.Close()
+}
+
+// SetResponse replaces the default response, served when no route matches.
+func (s *Server) SetResponse(resp *httplab.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resp = resp
+}
+
+// SetResponses replaces the responses list whose routes serve the requests.
+func (s *Server) SetResponses(rl *httplab.ResponsesList) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = rl
+}
+
+// Requests returns every request received so far, in arrival order.
+func (s *Server) Requests() []*httplab.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*httplab.Request(nil), s.requests...)
+}
+
+// WaitForRequest returns the oldest request that hasn't been returned by WaitForRequest yet,
+// blocking until it arrives or ctx is done.
+func (s *Server) WaitForRequest(ctx context.Context) (*httplab.Request, error) {
+	for {
+		s.mu.Lock()
+		if s.waited < len(s.requests) {
+			req := s.requests[s.waited]
+			s.waited++
+			s.mu.Unlock()
+			return req, nil
+		}
+		arrived := s.arrived
+		s.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Reset clears the recorded requests.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.waited = 0
+}
+
+func (s *Server) match(req *http.Request) (*httplab.Route, *httplab.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if route, resp := s.responses.Match(req); route != nil {
+		return route, resp
+	}
+	return nil, s.resp
+}
+
+func (s *Server) record(req *http.Request, route *httplab.Route, resp *httplab.Response) error {
+	r, err := httplab.NewRequest(req)
+	if err != nil {
+		return err
+	}
+	r.ServedBy(route, resp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	close(s.arrived)
+	s.arrived = make(chan struct{})
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	route, resp := s.match(req)
+	if err := s.record(req, route, resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Serve(w, req)
+}
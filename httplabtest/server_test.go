@@ -0,0 +1,167 @@
+package httplabtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gchaincl/httplab"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestServer(t *testing.T) {
+	resp, err := httplab.NewResponse("201", "X-Foo: bar", "created")
+	require.NoError(t, err)
+
+	srv := NewServer(resp)
+	defer srv.Close()
+
+	res, err := http.Post(srv.URL+"/users?x=1", "application/json", strings.NewReader(`{"name": "bob"}`))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, 201, res.StatusCode)
+	assert.Equal(t, "bar", res.Header.Get("X-Foo"))
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "POST", reqs[0].Method)
+	assert.Equal(t, "/users?x=1", reqs[0].URL)
+	assert.Equal(t, "application/json", reqs[0].Header().Get("Content-Type"))
+	assert.Equal(t, `{"name": "bob"}`, string(reqs[0].Body))
+
+	t.Run("SetResponse()", func(t *testing.T) {
+		resp, err := httplab.NewResponse("500", "", "boom")
+		require.NoError(t, err)
+		srv.SetResponse(resp)
+
+		status, body := get(t, srv.URL)
+		assert.Equal(t, 500, status)
+		assert.Equal(t, "boom", body)
+	})
+
+	t.Run("Reset()", func(t *testing.T) {
+		srv.Reset()
+		assert.Empty(t, srv.Requests())
+	})
+}
+
+func TestServerWaitForRequest(t *testing.T) {
+	srv := NewServer(&httplab.Response{Status: 200})
+	defer srv.Close()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		for _, path := range []string{"/first", "/second"} {
+			if resp, err := http.Get(srv.URL + path); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	req, err := srv.WaitForRequest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "/first", req.URL)
+
+	req, err = srv.WaitForRequest(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "/second", req.URL)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = srv.WaitForRequest(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestServerFromFile(t *testing.T) {
+	srv, err := NewServerFromFile("../testdata/routes.json")
+	require.NoError(t, err)
+	defer srv.Close()
+
+	status, body := get(t, srv.URL+"/users/1")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, `{"id": 1}`, body)
+
+	status, _ = get(t, srv.URL+"/unknown")
+	assert.Equal(t, 404, status)
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 2)
+	assert.Equal(t, "GET /users/*", reqs[0].Route)
+	assert.Equal(t, "user", reqs[0].Response)
+	assert.Equal(t, "", reqs[1].Route)
+}
+
+func TestServerFromMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	_, err := NewServerFromFile(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// the file isn't created either
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestServerEvents(t *testing.T) {
+	srv := NewServer(&httplab.Response{Status: 200, Body: httplab.Body{Mode: httplab.BodySSE}})
+
+	res, err := http.Get(srv.URL + "/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	_, err = srv.WaitForRequest(context.Background())
+	require.NoError(t, err)
+
+	// the open stream doesn't keep Close from returning
+	closed := make(chan struct{})
+	go func() {
+		srv.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close didn't return with an SSE client connected")
+	}
+}
+
+func TestServerSockets(t *testing.T) {
+	resp := &httplab.Response{Status: 200, WebSocket: &httplab.WebSocket{Echo: true}}
+	srv, other := NewServer(resp), NewServer(resp)
+	defer other.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("echo")))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "echo", string(data))
+
+	// each server has clients of its own
+	assert.Len(t, srv.Brokers.Sockets.Clients(), 1)
+	assert.Empty(t, other.Brokers.Sockets.Clients())
+
+	srv.Close()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err)
+}
@@ -0,0 +1,167 @@
+package httplab
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// placeholderRegexp matches the ${VAR} and ${VAR:-default} placeholders.
+var placeholderRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// envNameRegexp matches the variable names allowed on dotenv files.
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envFile holds the variables of the dotenv file loaded with LoadEnvFile.
+var envFile struct {
+	sync.RWMutex
+	vars map[string]string
+}
+
+// LookupEnv returns the value of the variable key, taken from the environment,
+// or from the dotenv file loaded with LoadEnvFile when it isn't set there.
+func LookupEnv(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+
+	envFile.RLock()
+	defer envFile.RUnlock()
+	value, ok := envFile.vars[key]
+	return value, ok
+}
+
+// LoadEnvFile loads the KEY=value lines of a dotenv file, replacing the variables loaded before.
+// Blank lines and # comments are skipped, values can be quoted and lines can start with "export".
+func LoadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envNameRegexp.MatchString(key) {
+			return fmt.Errorf("%s:%d: expected KEY=value, got %q", path, n, line)
+		}
+		vars[key] = envValue(strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	envFile.Lock()
+	defer envFile.Unlock()
+	envFile.vars = vars
+	return nil
+}
+
+// envValue unquotes a dotenv value: double quotes take escapes like \n, single quotes are taken literally,
+// and unquoted values end at a " #" comment.
+func envValue(value string) string {
+	if len(value) >= 2 {
+		switch quote := value[0]; {
+		case quote == '"' && value[len(value)-1] == '"':
+			if s, err := strconv.Unquote(value); err == nil {
+				return s
+			}
+			return value[1 : len(value)-1]
+		case quote == '\'' && value[len(value)-1] == '\'':
+			return value[1 : len(value)-1]
+		}
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// Interpolate replaces the ${VAR} and ${VAR:-default} placeholders of s with the value of VAR, see LookupEnv.
+// The default is taken when VAR is unset or empty. Placeholders without a default are left as they are
+// while VAR is unset, so that bodies like JavaScript template literals aren't broken.
+func Interpolate(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+
+	return placeholderRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		m := placeholderRegexp.FindStringSubmatch(match)
+		value, ok := LookupEnv(m[1])
+		switch {
+		case ok && (value != "" || m[2] == ""):
+			return value
+		case m[2] != "":
+			return m[3]
+		}
+		return match
+	})
+}
+
+// expandVar is the os.Expand mapping of ExpandPath, name may have a ":-default".
+func expandVar(name string) string {
+	name, def, hasDefault := strings.Cut(name, ":-")
+	if value, ok := LookupEnv(name); ok && (value != "" || !hasDefault) {
+		return value
+	}
+	return def
+}
+
+// placeholder is a value of a response as it was written on the config, along with its interpolation.
+type placeholder struct {
+	raw, value string
+}
+
+// restore returns the value as it was written, unless value was edited since it was interpolated.
+func (p placeholder) restore(value string) string {
+	if value == p.value {
+		return p.raw
+	}
+	return value
+}
+
+// placeholders are the values of a response which are interpolated, so that they're saved
+// with their placeholders intact.
+type placeholders struct {
+	body    placeholder
+	file    placeholder
+	headers map[string][]placeholder
+}
+
+// restoreHeaders returns the headers as they were written, except for the values edited since.
+func (p *placeholders) restoreHeaders(hdr http.Header) map[string]headerValues {
+	restored := make(map[string]headerValues, len(hdr))
+	for key, values := range hdr {
+		raws := p.headers[key]
+		restored[key] = make(headerValues, len(values))
+		for i, value := range values {
+			if len(raws) == len(values) {
+				value = raws[i].restore(value)
+			}
+			restored[key][i] = value
+		}
+	}
+	return restored
+}
+
+// KeepPlaceholders makes r save the values it shares with from, which were interpolated, with their placeholders.
+// It's meant for responses built after one loaded from the config.
+func (r *Response) KeepPlaceholders(from *Response) {
+	if from != nil {
+		r.placeholders = from.placeholders
+	}
+}
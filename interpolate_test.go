@@ -0,0 +1,129 @@
+package httplab
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setEnvFile loads a dotenv file with data for the duration of the test.
+func setEnvFile(t *testing.T, data string) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	require.NoError(t, LoadEnvFile(path))
+	t.Cleanup(func() {
+		envFile.Lock()
+		envFile.vars = nil
+		envFile.Unlock()
+	})
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	t.Setenv("HTTPLAB_SHADOWED", "from env")
+	setEnvFile(t, `
+# tokens
+HTTPLAB_TOKEN=abc123
+export HTTPLAB_HOST = api.local # inline comment
+HTTPLAB_QUOTED="a # b\nc"
+HTTPLAB_LITERAL='a\nb'
+HTTPLAB_EMPTY=
+HTTPLAB_SHADOWED=from file
+`)
+
+	for key, expected := range map[string]string{
+		"HTTPLAB_TOKEN":    "abc123",
+		"HTTPLAB_HOST":     "api.local",
+		"HTTPLAB_QUOTED":   "a # b\nc",
+		"HTTPLAB_LITERAL":  `a\nb`,
+		"HTTPLAB_EMPTY":    "",
+		"HTTPLAB_SHADOWED": "from env",
+	} {
+		value, ok := LookupEnv(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, expected, value, key)
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("OK=1\nnot a variable\n"), 0644))
+	assert.EqualError(t, LoadEnvFile(path), path+`:2: expected KEY=value, got "not a variable"`)
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("HTTPLAB_EMPTY", "")
+	setEnvFile(t, "HTTPLAB_TOKEN=abc123\n")
+
+	for input, expected := range map[string]string{
+		"Bearer ${HTTPLAB_TOKEN}":            "Bearer abc123",
+		"${HTTPLAB_UNSET:-http://localhost}": "http://localhost",
+		"${HTTPLAB_EMPTY:-default}":          "default",
+		"[${HTTPLAB_EMPTY}]":                 "[]",
+		"${HTTPLAB_UNSET:-}":                 "",
+		"`Hello ${name}`":                    "`Hello ${name}`",
+		"$HTTPLAB_TOKEN ${not a var}":        "$HTTPLAB_TOKEN ${not a var}",
+	} {
+		assert.Equal(t, expected, Interpolate(input), input)
+	}
+
+	assert.Equal(t, "/tmp/abc123/x", ExpandPath("/tmp/$HTTPLAB_TOKEN/${HTTPLAB_UNSET:-x}"))
+}
+
+func TestResponsePlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "abc123.json"), []byte("{}"), 0644))
+	t.Setenv("HTTPLAB_DIR", dir)
+	setEnvFile(t, "HTTPLAB_TOKEN=abc123\n")
+
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"Status": 200,
+		"Headers": {"authorization": "Bearer ${HTTPLAB_TOKEN}", "X-Host": ["${HTTPLAB_HOST:-localhost}", "b"]},
+		"Body": "{\"token\": \"${HTTPLAB_TOKEN}\"}"
+	}`), &resp))
+	assert.Equal(t, "Bearer abc123", resp.Headers.Get("Authorization"))
+	assert.Equal(t, []string{"localhost", "b"}, resp.Headers["X-Host"])
+	assert.Equal(t, `{"token": "abc123"}`, string(resp.Body.Payload()))
+
+	data, err := json.Marshal(&resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Authorization":"Bearer ${HTTPLAB_TOKEN}"`)
+	assert.Contains(t, string(data), `"X-Host":["${HTTPLAB_HOST:-localhost}","b"]`)
+	assert.Contains(t, string(data), `"Body":"{\"token\": \"${HTTPLAB_TOKEN}\"}"`)
+
+	// the edited values are saved as they are
+	edited := &Response{Status: 200, Headers: resp.Headers.Clone(), Body: Body{Mode: BodyInput, Input: []byte("edited")}}
+	edited.Headers.Set("X-Host", "other")
+	edited.KeepPlaceholders(&resp)
+	data, err = json.Marshal(edited)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Authorization":"Bearer ${HTTPLAB_TOKEN}"`)
+	assert.Contains(t, string(data), `"X-Host":"other"`)
+	assert.Contains(t, string(data), `"Body":"edited"`)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"File": "${HTTPLAB_DIR}/${HTTPLAB_TOKEN}.json"}`), &resp))
+	assert.Equal(t, filepath.Join(dir, "abc123.json"), resp.Body.File.Name())
+	data, err = json.Marshal(&resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"File":"${HTTPLAB_DIR}/${HTTPLAB_TOKEN}.json"`)
+	resp.Body.File.Close()
+}
+
+func TestSaveKeepsPlaceholders(t *testing.T) {
+	setEnvFile(t, "HTTPLAB_TOKEN=abc123\n")
+	path := filepath.Join(t.TempDir(), "httplab.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("Responses:\n  auth:\n    Status: 200\n    Body: ${HTTPLAB_TOKEN}\n"), 0644))
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	assert.Equal(t, "abc123", string(rl.Get("auth").Body.Payload()))
+
+	rl.Get("auth").Status = 201
+	require.NoError(t, rl.Save(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Body: ${HTTPLAB_TOKEN}\n")
+	assert.NotContains(t, string(data), "abc123")
+}
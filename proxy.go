@@ -0,0 +1,106 @@
+package httplab
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Interceptor edits the upstream responses before they're sent to the client.
+type Interceptor interface {
+	// Intercepting tells whether the response to req has to be intercepted.
+	Intercepting(req *http.Request) bool
+	// Intercept returns the response to send instead of resp.
+	Intercept(req *http.Request, resp *Response) (*Response, error)
+}
+
+// Proxy forwards the requests to an upstream server.
+type Proxy struct {
+	*httputil.ReverseProxy
+	Target *url.URL
+	// Interceptor, when set, can edit the upstream responses.
+	Interceptor Interceptor
+}
+
+// NewProxy returns a Proxy forwarding the requests to target, its path is prepended to the request one.
+// The Host header is the target one, the original is sent as X-Forwarded-Host.
+// When the upstream can't be reached a Bad Gateway is sent.
+func NewProxy(target string) (*Proxy, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid upstream '%s', expected scheme://host[/path]", target)
+	}
+
+	p := &Proxy{Target: u}
+	p.ReverseProxy = httputil.NewSingleHostReverseProxy(u)
+	director := p.Director
+	p.Director = func(req *http.Request) {
+		if req.Header.Get("X-Forwarded-Host") == "" {
+			req.Header.Set("X-Forwarded-Host", req.Host)
+		}
+		director(req)
+		req.Host = u.Host
+		if p.intercepting(req) {
+			// the response has to be editable
+			req.Header.Del("Accept-Encoding")
+		}
+	}
+	p.ModifyResponse = p.modifyResponse
+	p.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+	p.ErrorLog = log.New(io.Discard, "", 0)
+	return p, nil
+}
+
+func (p *Proxy) intercepting(req *http.Request) bool {
+	return p.Interceptor != nil && p.Interceptor.Intercepting(req)
+}
+
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	if !p.intercepting(resp.Request) {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	upstream := &Response{
+		Status:  resp.StatusCode,
+		Headers: resp.Header.Clone(),
+		Body:    Body{Mode: BodyInput, Input: body},
+	}
+	upstream.Headers.Del("Content-Length")
+
+	edited, err := p.Interceptor.Intercept(resp.Request, upstream)
+	if err != nil {
+		return err
+	}
+	time.Sleep(edited.Delay.Duration())
+
+	payload := edited.Body.Payload()
+	resp.StatusCode = edited.Status
+	resp.Status = fmt.Sprintf("%d %s", edited.Status, http.StatusText(edited.Status))
+	resp.Header = edited.Headers.Clone()
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	resp.Header.Set("Content-Length", strconv.Itoa(len(payload)))
+	resp.Header.Del("Transfer-Encoding")
+	resp.TransferEncoding = nil
+	resp.ContentLength = int64(len(payload))
+	resp.Body = io.NopCloser(bytes.NewReader(payload))
+	return nil
+}
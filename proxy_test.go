@@ -0,0 +1,108 @@
+package httplab
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testInterceptor struct {
+	intercepting bool
+	got          *Response
+	edit         func(*Response) *Response
+}
+
+func (i *testInterceptor) Intercepting(*http.Request) bool { return i.intercepting }
+
+func (i *testInterceptor) Intercept(req *http.Request, resp *Response) (*Response, error) {
+	i.got = resp
+	return i.edit(resp), nil
+}
+
+func newUpstream(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Path", req.URL.Path)
+		w.Header().Set("X-Host", req.Host)
+		w.Header().Set("X-Forwarded", req.Header.Get("X-Forwarded-Host"))
+		w.Header().Set("X-Encoding", req.Header.Get("Accept-Encoding"))
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "upstream")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProxy(t *testing.T) {
+	upstream := newUpstream(t)
+	proxy, err := NewProxy(upstream.URL + "/api")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "http://httplab.local/users", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "upstream", w.Body.String())
+	assert.Equal(t, "/api/users", w.Header().Get("X-Path"))
+	assert.Equal(t, proxy.Target.Host, w.Header().Get("X-Host"))
+	assert.Equal(t, "httplab.local", w.Header().Get("X-Forwarded"))
+	assert.Equal(t, "gzip", w.Header().Get("X-Encoding"))
+
+	_, err = NewProxy("localhost:8080")
+	assert.Error(t, err)
+}
+
+func TestProxyIntercept(t *testing.T) {
+	upstream := newUpstream(t)
+	proxy, err := NewProxy(upstream.URL)
+	require.NoError(t, err)
+
+	i := &testInterceptor{intercepting: true, edit: func(resp *Response) *Response {
+		edited := *resp
+		edited.Status = http.StatusOK
+		edited.Headers = resp.Headers.Clone()
+		edited.Headers.Set("X-Edited", "yes")
+		edited.Body = Body{Mode: BodyInput, Input: []byte("edited body")}
+		return &edited
+	}}
+	proxy.Interceptor = i
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, req)
+
+	require.NotNil(t, i.got)
+	assert.Equal(t, http.StatusTeapot, i.got.Status)
+	assert.Equal(t, "upstream", string(i.got.Body.Payload()))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "edited body", w.Body.String())
+	assert.Equal(t, "yes", w.Header().Get("X-Edited"))
+	assert.Equal(t, "11", w.Header().Get("Content-Length"))
+
+	t.Run("Not intercepting", func(t *testing.T) {
+		i.intercepting, i.got = false, nil
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Nil(t, i.got)
+		assert.Equal(t, "upstream", w.Body.String())
+	})
+}
+
+func TestProxyBadGateway(t *testing.T) {
+	upstream := newUpstream(t)
+	upstream.Close()
+
+	proxy, err := NewProxy(upstream.URL)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
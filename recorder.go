@@ -0,0 +1,198 @@
+package httplab
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DuplicateMode tells how a Recorder handles a response whose route has already been recorded.
+type DuplicateMode string
+
+// DuplicateMode values
+const (
+	// DuplicateFirst keeps the first recorded response.
+	DuplicateFirst DuplicateMode = "first"
+	// DuplicateLast replaces the recorded response with the new one.
+	DuplicateLast DuplicateMode = "last"
+	// DuplicateSequence appends the new response to a sequence, played back in the recorded order.
+	DuplicateSequence DuplicateMode = "sequence"
+)
+
+// ParseDuplicateMode validates a DuplicateMode name.
+func ParseDuplicateMode(name string) (DuplicateMode, error) {
+	switch m := DuplicateMode(name); m {
+	case DuplicateFirst, DuplicateLast, DuplicateSequence:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown duplicate mode '%s', expected first, last or sequence", name)
+}
+
+// recordedHeaders aren't recorded, they're computed when the response is served.
+var recordedHeaders = []string{"Content-Length", "Date", "Transfer-Encoding", "Connection", "Keep-Alive", "Trailer"}
+
+// Recorder turns replies into saved responses, with a route matching the method and path of their request.
+// The responses are saved into the config file at Path, bodies which aren't valid UTF-8 are stored in files under BodyDir.
+type Recorder struct {
+	Path       string
+	BodyDir    string
+	Duplicates DuplicateMode
+
+	mu sync.Mutex
+}
+
+// NewRecorder returns a Recorder saving into the config file at path.
+func NewRecorder(path string, duplicates DuplicateMode) *Recorder {
+	path = ExpandPath(path)
+	return &Recorder{
+		Path:       path,
+		BodyDir:    path + ".bodies",
+		Duplicates: duplicates,
+	}
+}
+
+// RecordedKey is the name given to the response recorded for method and path.
+func RecordedKey(method, path string) string {
+	return method + " " + path
+}
+
+// Record saves the reply of r, it returns the name of the saved response.
+// The name is empty when the reply wasn't recorded because of DuplicateFirst.
+func (rec *Recorder) Record(r *Request) (string, error) {
+	if r.Reply == nil {
+		return "", fmt.Errorf("request %s %s has no reply to record", r.Method, r.URL)
+	}
+
+	u, err := url.ParseRequestURI(r.URL)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := rec.response(r.Reply)
+	if err != nil {
+		return "", err
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rl := NewResponsesList()
+	if err := rl.Load(rec.Path); err != nil {
+		return "", err
+	}
+	// the list is loaded on every request, its body files would pile up otherwise
+	defer rl.closeFiles()
+
+	key := RecordedKey(r.Method, u.Path)
+	route := rl.recordedRoute(r.Method, u.Path)
+	switch {
+	case route == nil:
+		rl.Add(key, resp)
+		rl.Routes = append(rl.Routes, &Route{Method: r.Method, Path: escapeGlob(u.Path), Response: key})
+	case rec.Duplicates == DuplicateFirst:
+		return "", nil
+	case rec.Duplicates == DuplicateSequence:
+		key = rl.appendRecorded(route, key, resp)
+	default:
+		if route.Sequence != "" {
+			// it was recorded as a sequence before, start it over
+			delete(rl.Sequences, route.Sequence)
+			route.Sequence = ""
+		}
+		route.Response = key
+		rl.Add(key, resp)
+	}
+
+	return key, rl.Save(rec.Path)
+}
+
+// response converts reply into a Response, reply must hold the whole body.
+func (rec *Recorder) response(reply *Reply) (*Response, error) {
+	if reply.Size > int64(len(reply.Body)) {
+		return nil, fmt.Errorf("only %d of the %d body bytes were kept", len(reply.Body), reply.Size)
+	}
+
+	resp := &Response{Status: reply.Status, Headers: http.Header{}}
+	for _, h := range reply.Headers {
+		resp.Headers.Add(h.Name, h.Value)
+	}
+	for _, name := range recordedHeaders {
+		resp.Headers.Del(name)
+	}
+	for _, h := range reply.Trailers {
+		if resp.Trailers == nil {
+			resp.Trailers = http.Header{}
+		}
+		resp.Trailers.Add(h.Name, h.Value)
+	}
+
+	if utf8.Valid(reply.Body) {
+		resp.Body = Body{Mode: BodyInput, Input: reply.Body}
+		return resp, nil
+	}
+
+	if err := os.MkdirAll(rec.BodyDir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(rec.BodyDir, fmt.Sprintf("%x.bin", sha1.Sum(reply.Body)))
+	if err := os.WriteFile(path, reply.Body, 0644); err != nil {
+		return nil, err
+	}
+
+	resp.Body.Mode = BodyFile
+	if err := resp.Body.SetFile(path); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// recordedRoute returns the route recorded for method and path, nil if there isn't one.
+func (rl *ResponsesList) recordedRoute(method, path string) *Route {
+	for _, route := range rl.Routes {
+		if route.Method == method && route.Path == escapeGlob(path) &&
+			route.Regexp == "" && len(route.Query) == 0 && len(route.Headers) == 0 {
+			return route
+		}
+	}
+	return nil
+}
+
+// appendRecorded adds resp to the sequence served by route, turning it into one if needed.
+func (rl *ResponsesList) appendRecorded(route *Route, key string, resp *Response) string {
+	seq, ok := rl.Sequences[route.Sequence]
+	if !ok {
+		seq = &Sequence{Mode: SequenceStopAtLast}
+		if route.Response != "" {
+			seq.Responses = append(seq.Responses, route.Response)
+		}
+		rl.Sequences[key] = seq
+		route.Sequence = key
+		route.Response = ""
+	}
+
+	name := key
+	for i := len(seq.Responses) + 1; rl.Get(name) != nil; i++ {
+		name = fmt.Sprintf("%s #%d", key, i)
+	}
+	seq.Responses = append(seq.Responses, name)
+	rl.Add(name, resp)
+	return name
+}
+
+// escapeGlob escapes the characters Route.Path would take as a pattern.
+func escapeGlob(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+package httplab
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRecorded(method, uri string, status int, body string) *Request {
+	return &Request{
+		Method: method,
+		URL:    uri,
+		Reply: &Reply{
+			Status: status,
+			Headers: []HeaderField{
+				{Name: "Content-Type", Value: "text/plain"},
+				{Name: "Content-Length", Value: "3"},
+				{Name: "Date", Value: "Thu, 01 Jun 2017 10:00:00 GMT"},
+			},
+			Body: []byte(body),
+		},
+	}
+}
+
+func serveRecorded(t *testing.T, rl *ResponsesList, method, target string) (*Route, *Response) {
+	route, resp := rl.Match(httptest.NewRequest(method, target, nil))
+	require.NotNil(t, route, "%s %s", method, target)
+	return route, resp
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "httplab.json")
+	rec := NewRecorder(path, DuplicateLast)
+
+	name, err := rec.Record(newRecorded("GET", "/users?page=1", 200, "one"))
+	require.NoError(t, err)
+	assert.Equal(t, "GET /users", name)
+
+	_, err = rec.Record(newRecorded("POST", "/users", 201, "created"))
+	require.NoError(t, err)
+	_, err = rec.Record(newRecorded("GET", "/users?page=2", 200, "two"))
+	require.NoError(t, err)
+	_, err = rec.Record(newRecorded("GET", "/files/*", 200, "glob"))
+	require.NoError(t, err)
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	assert.Equal(t, []string{"GET /files/*", "GET /users", "POST /users"}, rl.Keys())
+	assert.Len(t, rl.Routes, 3)
+
+	_, resp := serveRecorded(t, rl, "GET", "/users")
+	assert.Equal(t, "two", string(resp.Body.Payload()))
+	assert.Equal(t, "text/plain", resp.Headers.Get("Content-Type"))
+	assert.Empty(t, resp.Headers.Get("Content-Length"))
+	assert.Empty(t, resp.Headers.Get("Date"))
+
+	_, resp = serveRecorded(t, rl, "POST", "/users")
+	assert.Equal(t, 201, resp.Status)
+
+	route, _ := rl.Match(httptest.NewRequest("GET", "/files/x", nil))
+	assert.Nil(t, route, "recorded paths aren't patterns")
+
+	_, err = rec.Record(&Request{Method: "GET", URL: "/"})
+	assert.Error(t, err)
+}
+
+func TestRecorderFirst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "httplab.json")
+	rec := NewRecorder(path, DuplicateFirst)
+
+	_, err := rec.Record(newRecorded("GET", "/", 200, "first"))
+	require.NoError(t, err)
+	name, err := rec.Record(newRecorded("GET", "/", 200, "second"))
+	require.NoError(t, err)
+	assert.Empty(t, name)
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	_, resp := serveRecorded(t, rl, "GET", "/")
+	assert.Equal(t, "first", string(resp.Body.Payload()))
+}
+
+func TestRecorderSequence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "httplab.json")
+	rec := NewRecorder(path, DuplicateSequence)
+
+	for _, body := range []string{"1", "2", "3"} {
+		_, err := rec.Record(newRecorded("GET", "/poll", 200, body))
+		require.NoError(t, err)
+	}
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	require.Len(t, rl.Routes, 1)
+	assert.Equal(t, "GET /poll", rl.Routes[0].Sequence)
+	assert.Equal(t, []string{"GET /poll", "GET /poll #2", "GET /poll #3"}, rl.Sequences["GET /poll"].Responses)
+
+	var served []string
+	for i := 0; i < 4; i++ {
+		_, resp := serveRecorded(t, rl, "GET", "/poll")
+		served = append(served, string(resp.Body.Payload()))
+	}
+	assert.Equal(t, []string{"1", "2", "3", "3"}, served)
+
+	t.Run("Last after sequence", func(t *testing.T) {
+		rec.Duplicates = DuplicateLast
+		_, err := rec.Record(newRecorded("GET", "/poll", 200, "last"))
+		require.NoError(t, err)
+
+		rl := NewResponsesList()
+		require.NoError(t, rl.Load(path))
+		assert.Empty(t, rl.Sequences)
+		_, resp := serveRecorded(t, rl, "GET", "/poll")
+		assert.Equal(t, "last", string(resp.Body.Payload()))
+	})
+}
+
+func TestRecorderBinaryBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "httplab.json")
+	rec := NewRecorder(path, DuplicateLast)
+
+	r := newRecorded("GET", "/image.png", http.StatusOK, "")
+	r.Reply.Body = []byte{0x89, 'P', 'N', 'G', 0xff}
+	_, err := rec.Record(r)
+	require.NoError(t, err)
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	_, resp := serveRecorded(t, rl, "GET", "/image.png")
+	assert.Equal(t, BodyFile, resp.Body.Mode)
+	assert.Equal(t, r.Reply.Body, resp.Body.Payload())
+	assert.Equal(t, rec.BodyDir, filepath.Dir(resp.Body.File.Name()))
+}
+
+func TestRecorderClosesBodyFiles(t *testing.T) {
+	rec := NewRecorder(filepath.Join(t.TempDir(), "httplab.json"), DuplicateSequence)
+	record := func(i int) {
+		r := newRecorded("GET", "/image.png", http.StatusOK, "")
+		r.Reply.Body = []byte{0x89, 'P', 'N', 'G', byte(i)}
+		_, err := rec.Record(r)
+		require.NoError(t, err)
+	}
+
+	record(0)
+	open := openFiles(t)
+	for i := 1; i <= 10; i++ {
+		record(i)
+	}
+	assert.True(t, openFiles(t) <= open, "recordings leak no files")
+}
+
+func TestRecorderTruncatedBody(t *testing.T) {
+	rec := NewRecorder(filepath.Join(t.TempDir(), "httplab.json"), DuplicateLast)
+
+	r := newRecorded("GET", "/large", http.StatusOK, "first bytes")
+	r.Reply.Size = maxReplyBody + 1
+	_, err := rec.Record(r)
+	assert.EqualError(t, err, fmt.Sprintf("only 11 of the %d body bytes were kept", maxReplyBody+1))
+}
+
+func TestParseDuplicateMode(t *testing.T) {
+	m, err := ParseDuplicateMode("sequence")
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateSequence, m)
+
+	_, err = ParseDuplicateMode("all")
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package httplab
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ConfigDiff describes what changed between two versions of a config.
+type ConfigDiff struct {
+	// Added, Removed and Changed are response names.
+	Added   []string
+	Removed []string
+	Changed []string
+	// Sequences are the names of the sequences added, removed or changed.
+	Sequences []string
+	Routes    bool
+}
+
+func diffConfig(old, new snapshot) *ConfigDiff {
+	d := &ConfigDiff{Routes: !bytes.Equal(old.routes, new.routes)}
+	for name, data := range new.responses {
+		if prev, ok := old.responses[name]; !ok {
+			d.Added = append(d.Added, name)
+		} else if !bytes.Equal(prev, data) {
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for name := range old.responses {
+		if _, ok := new.responses[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	for name, data := range new.sequences {
+		if prev, ok := old.sequences[name]; !ok || !bytes.Equal(prev, data) {
+			d.Sequences = append(d.Sequences, name)
+		}
+	}
+	for name := range old.sequences {
+		if _, ok := new.sequences[name]; !ok {
+			d.Sequences = append(d.Sequences, name)
+		}
+	}
+
+	for _, names := range [][]string{d.Added, d.Removed, d.Changed, d.Sequences} {
+		sort.Strings(names)
+	}
+	return d
+}
+
+// Empty reports whether nothing changed.
+func (d *ConfigDiff) Empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Changed)+len(d.Sequences) == 0 && !d.Routes
+}
+
+// HasChanged reports whether the response name was changed.
+func (d *ConfigDiff) HasChanged(name string) bool {
+	for _, changed := range d.Changed {
+		if changed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// String summarizes the diff in a single line, like "added a, b; changed c; routes changed".
+func (d *ConfigDiff) String() string {
+	if d.Empty() {
+		return "nothing changed"
+	}
+
+	var parts []string
+	for _, part := range []struct {
+		label string
+		names []string
+	}{
+		{"added", d.Added},
+		{"removed", d.Removed},
+		{"changed", d.Changed},
+		{"sequences changed", d.Sequences},
+	} {
+		if len(part.names) > 0 {
+			parts = append(parts, part.label+" "+strings.Join(part.names, ", "))
+		}
+	}
+	if d.Routes {
+		parts = append(parts, "routes changed")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Reload loads the config files again when one of them was modified since the list was loaded or saved,
+// or one of paths was created meanwhile, it returns what changed. paths are the ones given to Load.
+// The diff is nil when nothing was modified. When they can't be loaded, the list is left untouched.
+func (rl *ResponsesList) Reload(paths ...string) (*ConfigDiff, error) {
+	changed, err := rl.modified(paths)
+	if err != nil || !changed {
+		return nil, err
+	}
+
+	old, err := takeSnapshot(rl.config())
+	if err != nil {
+		return nil, err
+	}
+
+	// loaded aside, so that the list is kept on errors
+	next := &ResponsesList{Sequences: rl.Sequences}
+	if err := next.Load(paths...); err != nil {
+		return nil, err
+	}
+	new, err := takeSnapshot(next.config())
+	if err != nil {
+		return nil, err
+	}
+
+	rl.reuseFiles(next)
+	*rl = *next
+	return diffConfig(old, new), nil
+}
+
+// reuseFiles makes the responses of next serve the body files rl already has open for the same paths,
+// so that the responses in use keep theirs, and closes the files of rl left unused.
+func (rl *ResponsesList) reuseFiles(next *ResponsesList) {
+	open := make(map[string]*os.File)
+	for _, resp := range rl.List {
+		if f := resp.Body.File; f != nil {
+			open[f.Name()] = f
+		}
+	}
+
+	used := make(map[*os.File]bool)
+	for _, resp := range next.List {
+		f := resp.Body.File
+		if f == nil {
+			continue
+		}
+		if prev, ok := open[f.Name()]; ok {
+			f.Close()
+			resp.Body.File = prev
+			used[prev] = true
+		}
+	}
+
+	for _, f := range open {
+		if !used[f] {
+			f.Close()
+		}
+	}
+}
+
+// closeFiles closes the body files of the responses, for lists which are no longer used.
+func (rl *ResponsesList) closeFiles() {
+	for _, resp := range rl.List {
+		if resp.Body.File != nil {
+			resp.Body.File.Close()
+		}
+	}
+}
+
+// modified reports whether one of the config files was modified since it was loaded or saved,
+// or one of paths, which didn't exist, was created meanwhile.
+func (rl *ResponsesList) modified(paths []string) (bool, error) {
+	for _, file := range rl.files {
+		if changed, err := file.changed(file.path); err != nil || changed {
+			return changed, err
+		}
+	}
+	for _, path := range paths {
+		if rl.file(path) != nil {
+			continue
+		}
+		if changed, err := (&diskState{}).changed(path); err != nil || changed {
+			return changed, err
+		}
+	}
+	return false, nil
+}
+
+// config returns every entry of the list, wherever they come from.
+func (rl *ResponsesList) config() *config {
+	return &config{Responses: rl.List, Routes: rl.Routes, Sequences: rl.Sequences}
+}
@@ -0,0 +1,142 @@
+package httplab
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".httplab")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"Responses": {"kept": {"Status": 200}, "changed": {"Status": 200}, "removed": {"Status": 200}}
+	}`), 0644))
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+
+	diff, err := rl.Reload(path)
+	require.NoError(t, err)
+	assert.Nil(t, diff, "nothing changed yet")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"Responses": {"kept": {"Status": 200}, "changed": {"Status": 500}, "added": {"Status": 201}},
+		"Routes": [{"Path": "/added", "Response": "added"}]
+	}`), 0644))
+	diff, err = rl.Reload(path)
+	require.NoError(t, err)
+	require.NotNil(t, diff)
+	assert.Equal(t, []string{"added"}, diff.Added)
+	assert.Equal(t, []string{"removed"}, diff.Removed)
+	assert.True(t, diff.HasChanged("changed"))
+	assert.False(t, diff.HasChanged("kept"))
+	assert.Equal(t, "added added; removed removed; changed changed; routes changed", diff.String())
+	assert.Equal(t, 500, rl.Get("changed").Status)
+	assert.Len(t, rl.Routes, 1)
+
+	// the last good config is kept
+	require.NoError(t, os.WriteFile(path, []byte(`{"Responses": {`), 0644))
+	_, err = rl.Reload(path)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"added", "changed", "kept"}, rl.Keys())
+
+	// saving over the broken file would lose what's being edited
+	assert.IsType(t, &ConflictError{}, rl.Save(path))
+}
+
+func TestReloadKeepsSequencePositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".httplab")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"Responses": {"a": {"Status": 200}, "b": {"Status": 201}},
+		"Sequences": {"s": {"Responses": ["a", "b"]}}
+	}`), 0644))
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	rl.Sequences["s"].Next()
+
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"Responses": {"a": {"Status": 200}, "b": {"Status": 202}},
+		"Sequences": {"s": {"Responses": ["a", "b"]}}
+	}`), 0644))
+	diff, err := rl.Reload(path)
+	require.NoError(t, err)
+	assert.Empty(t, diff.Sequences)
+	assert.Equal(t, uint64(1), rl.Sequences["s"].Position())
+}
+
+func TestReloadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigs(t, dir, map[string]string{
+		".httplab":      `{"Include": ["lib/*.json"], "Responses": {"a": {"Status": 200}}}`,
+		"lib/one.json":  `{"Responses": {"b": {"Status": 200}}}`,
+		"user/.httplab": `{"Responses": {"c": {"Status": 200}}}`,
+	})
+	user, project := filepath.Join(dir, "user", ".httplab"), filepath.Join(dir, ".httplab")
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(user, project))
+
+	writeConfigs(t, dir, map[string]string{"lib/one.json": `{"Responses": {"b": {"Status": 500}}}`})
+	diff, err := rl.Reload(user, project)
+	require.NoError(t, err)
+	require.NotNil(t, diff)
+	assert.Equal(t, []string{"b"}, diff.Changed)
+
+	writeConfigs(t, dir, map[string]string{"user/.httplab": `{"Responses": {"a": {"Status": 201}}}`})
+	diff, err = rl.Reload(user, project)
+	require.NoError(t, err)
+	require.NotNil(t, diff)
+	assert.Equal(t, "removed c", diff.String(), "a is overlaid by the project file")
+}
+
+// openFiles returns the amount of file descriptors open by the process,
+// it can only be compared as an upper bound since files leaked elsewhere get closed by the GC.
+func openFiles(t *testing.T) int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("open files can't be counted: ", err)
+	}
+	return len(entries)
+}
+
+func TestReloadBodyFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kept.bin"), []byte("kept"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dropped.bin"), []byte("dropped"), 0644))
+	path := filepath.Join(dir, ".httplab")
+	write := func(i int, files ...string) {
+		responses := make([]string, len(files))
+		for j, file := range files {
+			responses[j] = fmt.Sprintf(`"%d": {"Status": %d, "File": %q}`, j, 200+i, filepath.Join(dir, file))
+		}
+		require.NoError(t, os.WriteFile(path, []byte(`{"Responses": {`+strings.Join(responses, ", ")+`}}`), 0644))
+	}
+
+	write(0, "kept.bin", "dropped.bin")
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	kept, dropped := rl.Get("0"), rl.Get("1")
+	open := openFiles(t)
+
+	for i := 1; i <= 10; i++ {
+		write(i, "kept.bin", "dropped.bin")
+		diff, err := rl.Reload(path)
+		require.NoError(t, err)
+		require.NotNil(t, diff)
+	}
+	assert.True(t, openFiles(t) <= open, "reloads leak no files")
+
+	// the responses in use keep serving their files, the ones no longer used are closed
+	write(11, "kept.bin")
+	_, err := rl.Reload(path)
+	require.NoError(t, err)
+	assert.Equal(t, "kept", string(kept.Body.Payload()))
+	assert.Equal(t, kept.Body.File, rl.Get("0").Body.File)
+	assert.Error(t, dropped.Body.File.Close(), "already closed")
+}
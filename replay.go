@@ -0,0 +1,148 @@
+package httplab
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// replayClient doesn't follow redirects, so the upstream response is shown as it is.
+var replayClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// hopHeaders aren't replayed, the client sets them on its own.
+var hopHeaders = []string{"Host", "Content-Length", "Transfer-Encoding", "Connection"}
+
+// ParseHeaderOverrides parses "Name: value" lines, an empty value removes the header when replaying.
+func ParseHeaderOverrides(lines []string) (http.Header, error) {
+	hdr := http.Header{}
+	for _, line := range lines {
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid header '%s', expected 'Name: value'", line)
+		}
+		key := http.CanonicalHeaderKey(strings.TrimSpace(kv[0]))
+		hdr[key] = append(hdr[key], strings.TrimSpace(kv[1]))
+	}
+	return hdr, nil
+}
+
+// ReplayURL joins the target base URL with the request URI.
+func (r *Request) ReplayURL(target string) (*url.URL, error) {
+	base, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid target '%s', expected scheme://host[/path]", target)
+	}
+
+	uri, err := url.ParseRequestURI(r.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "/") + uri.Path
+	u.RawPath = ""
+	switch {
+	case base.RawQuery == "":
+		u.RawQuery = uri.RawQuery
+	case uri.RawQuery != "":
+		u.RawQuery = base.RawQuery + "&" + uri.RawQuery
+	}
+	return &u, nil
+}
+
+// Replay sends the request again to target, the stored path is appended to the target path.
+// Headers in overrides replace the captured ones, the empty ones are removed.
+// A nil client doesn't follow redirects.
+// The returned Request is the one sent, the upstream response is its Reply.
+func (r *Request) Replay(ctx context.Context, client *http.Client, target string, overrides http.Header) (*Request, error) {
+	if client == nil {
+		client = replayClient
+	}
+
+	u, err := r.ReplayURL(target)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(r.Method, u.String(), bytes.NewReader(r.Body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header = r.Header()
+	for _, name := range hopHeaders {
+		req.Header.Del(name)
+	}
+	for name, values := range overrides {
+		if name == "Host" {
+			req.Host = values[0]
+			continue
+		}
+		req.Header.Del(name)
+		for _, value := range values {
+			if value != "" {
+				req.Header.Add(name, value)
+			}
+		}
+	}
+
+	sent := &Request{
+		Method:     req.Method,
+		URL:        u.RequestURI(),
+		Host:       valueOrDefault(req.Host, u.Host),
+		Proto:      "HTTP/1.1",
+		Headers:    orderedHeaders(req.Header, nil),
+		Body:       r.Body,
+		ReceivedAt: time.Now(),
+		ReplayedTo: u.String(),
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reply := &Reply{
+		Proto:     resp.Proto,
+		Status:    resp.StatusCode,
+		Headers:   orderedHeaders(resp.Header, nil),
+		StartedAt: time.Now(),
+	}
+	if resp.TLS != nil {
+		sent.TLS = &TLSInfo{
+			Version:     TLSVersionName(resp.TLS.Version),
+			CipherSuite: tls.CipherSuiteName(resp.TLS.CipherSuite),
+			ServerName:  resp.TLS.ServerName,
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxReplyBody))
+	if err != nil {
+		return nil, err
+	}
+	rest, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	reply.Body = body
+	reply.Size = int64(len(body)) + rest
+	reply.SentAt = time.Now()
+
+	sent.Reply = reply
+	return sent, nil
+}
@@ -0,0 +1,113 @@
+package httplab
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeaderOverrides(t *testing.T) {
+	hdr, err := ParseHeaderOverrides([]string{"x-foo: bar", "X-Foo:baz", "Cookie:"})
+	require.NoError(t, err)
+	assert.Equal(t, http.Header{"X-Foo": {"bar", "baz"}, "Cookie": {""}}, hdr)
+
+	_, err = ParseHeaderOverrides([]string{"no colon"})
+	assert.Error(t, err)
+}
+
+func TestRequestReplayURL(t *testing.T) {
+	r := &Request{URL: "/hook?a=1"}
+	for target, expected := range map[string]string{
+		"http://localhost:8080":          "http://localhost:8080/hook?a=1",
+		"http://localhost:8080/":         "http://localhost:8080/hook?a=1",
+		"https://example.com/api?key=x":  "https://example.com/api/hook?key=x&a=1",
+		"https://example.com/api/?key=x": "https://example.com/api/hook?key=x&a=1",
+	} {
+		u, err := r.ReplayURL(target)
+		require.NoError(t, err)
+		assert.Equal(t, expected, u.String(), target)
+	}
+
+	_, err := r.ReplayURL("localhost:8080")
+	assert.Error(t, err)
+}
+
+func TestRequestReplay(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req
+		body, _ = io.ReadAll(req.Body)
+		w.Header().Set("Location", "/elsewhere")
+		w.WriteHeader(http.StatusFound)
+		io.WriteString(w, "moved")
+	}))
+	defer srv.Close()
+
+	r := &Request{
+		Method: "POST",
+		URL:    "/webhook?id=1",
+		Host:   "hooks.example.com",
+		Proto:  "HTTP/1.1",
+		Headers: []HeaderField{
+			{"Content-Type", "application/json"},
+			{"Content-Length", "7"},
+			{"X-Signature", "abc"},
+			{"X-Remove", "me"},
+		},
+		Body:  []byte(`{"a":1}`),
+		Route: "POST /webhook",
+	}
+
+	overrides := http.Header{"X-Signature": {"def"}, "X-Remove": {""}}
+	sent, err := r.Replay(context.Background(), nil, srv.URL+"/base", overrides)
+	require.NoError(t, err)
+
+	assert.Equal(t, "POST", got.Method)
+	assert.Equal(t, "/base/webhook?id=1", got.RequestURI)
+	assert.Equal(t, "def", got.Header.Get("X-Signature"))
+	assert.Empty(t, got.Header.Get("X-Remove"))
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, `{"a":1}`, string(body))
+
+	assert.Equal(t, srv.URL+"/base/webhook?id=1", sent.ReplayedTo)
+	assert.Equal(t, "/base/webhook?id=1", sent.URL)
+	assert.Empty(t, sent.Route)
+	assert.Contains(t, sent.Headers, HeaderField{"X-Signature", "def"})
+
+	// redirects aren't followed
+	require.NotNil(t, sent.Reply)
+	assert.Equal(t, http.StatusFound, sent.Reply.Status)
+	assert.Equal(t, "HTTP/1.1", sent.Reply.Proto)
+	assert.Equal(t, "moved", string(sent.Reply.Body))
+	assert.Equal(t, int64(5), sent.Reply.Size)
+	assert.Contains(t, sent.Reply.Headers, HeaderField{"Location", "/elsewhere"})
+
+	// the captured request is untouched
+	assert.Equal(t, "/webhook?id=1", r.URL)
+	assert.Nil(t, r.Reply)
+
+	t.Run("Host override", func(t *testing.T) {
+		sent, err := r.Replay(context.Background(), nil, srv.URL, http.Header{"Host": {"api.example.com"}})
+		require.NoError(t, err)
+		assert.Equal(t, "api.example.com", got.Host)
+		assert.Equal(t, "api.example.com", sent.Host)
+	})
+}
+
+func TestReplyDump(t *testing.T) {
+	reply := &Reply{
+		Status:  201,
+		Headers: []HeaderField{{"X-B", "2"}, {"Content-Type", "application/json"}},
+		Body:    []byte(`{"a":1}`),
+		Size:    10,
+	}
+	buf, err := reply.Dump()
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 201 Created\nContent-Type: application/json\nX-B: 2\n\n{\n  \"a\": 1\n}\n... 3 more bytes", string(Decolorize(buf)))
+}
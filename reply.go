@@ -0,0 +1,116 @@
+package httplab
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+)
+
+// maxReplyBody is the amount of bytes of the response body kept by default by a ReplyRecorder.
+const maxReplyBody = 1 << 20
+
+// Reply is the response HTTPLab sent to a Request.
+type Reply struct {
+	// Proto is only known for replays, HTTPLab replies with the request protocol.
+	Proto   string `json:",omitempty"`
+	Status  int
+	Headers []HeaderField
+	// Body holds up to the first MB of the response body, unless it was recorded with a greater MaxBody.
+	Body []byte
+	// Size is the amount of body bytes sent.
+	Size int64
+	// Trailers are the trailers sent after the body.
+	Trailers []HeaderField `json:",omitempty"`
+	// StartedAt is when the headers were sent.
+	StartedAt time.Time
+	// SentAt is when the response was completed.
+	SentAt time.Time
+}
+
+// ReplyRecorder is an http.ResponseWriter recording the response written through it.
+type ReplyRecorder struct {
+	http.ResponseWriter
+	// MaxBody is the amount of body bytes kept, a negative one keeps the whole body.
+	MaxBody int
+	reply   Reply
+}
+
+// NewReplyRecorder wraps w, keeping up to the first MB of the body.
+func NewReplyRecorder(w http.ResponseWriter) *ReplyRecorder {
+	return &ReplyRecorder{ResponseWriter: w, MaxBody: maxReplyBody}
+}
+
+// WriteHeader records the status and headers before sending them.
+func (rec *ReplyRecorder) WriteHeader(status int) {
+	if rec.reply.Status == 0 {
+		rec.reply.Status = status
+		rec.reply.StartedAt = time.Now()
+		rec.reply.Headers = orderedHeaders(rec.Header(), nil)
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body before sending it.
+func (rec *ReplyRecorder) Write(p []byte) (int, error) {
+	if rec.reply.Status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+
+	n, err := rec.ResponseWriter.Write(p)
+	rec.reply.Size += int64(n)
+	if rec.MaxBody < 0 {
+		rec.reply.Body = append(rec.reply.Body, p[:n]...)
+	} else if room := rec.MaxBody - len(rec.reply.Body); room > 0 {
+		if room > n {
+			room = n
+		}
+		rec.reply.Body = append(rec.reply.Body, p[:room]...)
+	}
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the underlying ResponseWriter supports it.
+func (rec *ReplyRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, if the underlying ResponseWriter supports it.
+func (rec *ReplyRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rec.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Push initiates an HTTP/2 server push, if the underlying ResponseWriter supports it.
+func (rec *ReplyRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rec.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying ResponseWriter, see http.ResponseController.
+func (rec *ReplyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Reply returns the recorded response, as sent so far.
+func (rec *ReplyRecorder) Reply() *Reply {
+	reply := rec.reply
+	reply.SentAt = time.Now()
+	if reply.StartedAt.IsZero() {
+		reply.StartedAt = reply.SentAt
+	}
+
+	trailers := http.Header{}
+	for _, name := range rec.Header()["Trailer"] {
+		name = http.CanonicalHeaderKey(name)
+		trailers[name] = rec.Header()[name]
+	}
+	reply.Trailers = orderedHeaders(trailers, nil)
+	return &reply
+}
@@ -0,0 +1,158 @@
+package httplab
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// HeaderField is a single header line.
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+// TLSInfo describes the TLS connection a request was received on.
+type TLSInfo struct {
+	Version     string
+	CipherSuite string
+	ServerName  string
+}
+
+// Request is a captured request.
+type Request struct {
+	Method string
+	// URL is the request URI as sent by the client.
+	URL   string
+	Host  string
+	Proto string
+	// StreamID is the HTTP/2 stream the request was received on, when it's known.
+	StreamID uint32 `json:",omitempty"`
+	// PseudoHeaders are the HTTP/2 pseudo-headers, like :method and :path.
+	PseudoHeaders []HeaderField `json:",omitempty"`
+	// Headers are in the order they were received when it's known, sorted by name otherwise.
+	Headers    []HeaderField
+	Body       []byte
+	RemoteAddr string
+	ReceivedAt time.Time
+	TLS        *TLSInfo `json:",omitempty"`
+	// Route is the route that matched the request, if any.
+	Route string `json:",omitempty"`
+	// Sequence is the name of the sequence that served the request, if any.
+	Sequence string `json:",omitempty"`
+	// Response is the name of the saved response served, empty if it wasn't a saved one.
+	Response string `json:",omitempty"`
+	// Reply is the response sent, nil until it's been sent. For replays it's the upstream response.
+	Reply *Reply `json:",omitempty"`
+	// Upstream is the URL the request was forwarded to, empty if it was served by HTTPLab.
+	Upstream string `json:",omitempty"`
+	// ReplayedTo is the URL the request was replayed to, empty for captured requests.
+	ReplayedTo string `json:",omitempty"`
+}
+
+// NewRequest captures req. Its body is read but remains available to subsequent readers.
+func NewRequest(req *http.Request) (*Request, error) {
+	r := &Request{
+		Method:     valueOrDefault(req.Method, "GET"),
+		URL:        req.RequestURI,
+		Host:       req.Host,
+		Proto:      valueOrDefault(req.Proto, "HTTP/1.1"),
+		RemoteAddr: req.RemoteAddr,
+		ReceivedAt: time.Now(),
+	}
+
+	if r.URL == "" {
+		r.URL = req.URL.RequestURI()
+	}
+
+	state := req.TLS
+	var order []string
+	if c := connFromContext(req.Context()); c != nil {
+		if req.ProtoMajor == 2 {
+			r.StreamID, r.PseudoHeaders, order = c.http2Headers(req)
+		} else {
+			order = c.headerOrder(req)
+		}
+		if state == nil {
+			state = c.tlsState()
+		}
+	}
+	if req.ProtoMajor == 2 && r.PseudoHeaders == nil {
+		r.PseudoHeaders = pseudoHeaders(req, state != nil)
+	}
+	r.Headers = orderedHeaders(req.Header, order)
+
+	if state != nil {
+		r.TLS = &TLSInfo{
+			Version:     TLSVersionName(state.Version),
+			CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+			ServerName:  state.ServerName,
+		}
+	}
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		// let the body be read again by the handler
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		r.Body = body
+	}
+
+	return r, nil
+}
+
+// orderedHeaders flattens hdr following the order of names.
+// Headers not listed in names are appended sorted by name.
+func orderedHeaders(hdr http.Header, names []string) []HeaderField {
+	var fields []HeaderField
+	used := make(map[string]int)
+	for _, name := range names {
+		values := hdr[name]
+		if used[name] >= len(values) {
+			continue
+		}
+		fields = append(fields, HeaderField{name, values[used[name]]})
+		used[name]++
+	}
+
+	var rest []string
+	for name := range hdr {
+		if used[name] < len(hdr[name]) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	for _, name := range rest {
+		for _, value := range hdr[name][used[name]:] {
+			fields = append(fields, HeaderField{name, value})
+		}
+	}
+
+	return fields
+}
+
+// ServedBy records the route, which can be nil, and the response the request has been served with.
+func (r *Request) ServedBy(route *Route, resp *Response) {
+	if route != nil {
+		r.Route = route.String()
+		r.Sequence = route.Sequence
+	}
+	if resp != nil {
+		r.Response = resp.Name
+	}
+}
+
+// Header returns the request headers as an http.Header.
+func (r *Request) Header() http.Header {
+	hdr := http.Header{}
+	for _, f := range r.Headers {
+		hdr.Add(f.Name, f.Value)
+	}
+	return hdr
+}
@@ -0,0 +1,136 @@
+package httplab
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCaptureServer starts a server capturing the requests it receives into ch.
+func newCaptureServer(t *testing.T, ch chan<- *Request) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r, err := NewRequest(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// body has to be still readable
+		body, _ := io.ReadAll(req.Body)
+		w.Write(body)
+		ch <- r
+	}))
+	srv.Listener = NewListener(srv.Listener)
+	srv.Config.ConnContext = ConnContext
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewRequestKeepsHeaderOrder(t *testing.T) {
+	ch := make(chan *Request, 2)
+	srv := newCaptureServer(t, ch)
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// two requests on the same connection
+	fmt.Fprint(conn, "POST /first?a=1 HTTP/1.1\r\nHost: localhost\r\nZ-Last: 1\r\nx-lower: 2\r\nA-First: 3\r\nZ-Last: 4\r\nContent-Length: 4\r\n\r\nbody")
+	fmt.Fprint(conn, "GET /second HTTP/1.1\r\nHost: localhost\r\nB: 1\r\nA: 2\r\n\r\n")
+
+	r := <-ch
+	assert.Equal(t, "POST", r.Method)
+	assert.Equal(t, "/first?a=1", r.URL)
+	assert.Equal(t, "localhost", r.Host)
+	assert.Equal(t, "HTTP/1.1", r.Proto)
+	assert.Equal(t, "body", string(r.Body))
+	assert.Equal(t, []HeaderField{
+		{"Z-Last", "1"},
+		{"X-Lower", "2"},
+		{"A-First", "3"},
+		{"Z-Last", "4"},
+		{"Content-Length", "4"},
+	}, r.Headers)
+
+	r = <-ch
+	assert.Equal(t, "/second", r.URL)
+	assert.Equal(t, []HeaderField{{"B", "1"}, {"A", "2"}}, r.Headers)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "body", string(body))
+}
+
+func TestNewRequestWithTLS(t *testing.T) {
+	cert, err := GenerateCertificate()
+	require.NoError(t, err)
+	tlsCert, err := cert.TLSCertificate()
+	require.NoError(t, err)
+
+	ch := make(chan *Request, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r, _ := NewRequest(req)
+		ch <- r
+	}))
+	// the handler doesn't get a *tls.Conn, so TLS info has to come from the wrapped connection
+	srv.Listener = NewListener(tls.NewListener(srv.Listener, &tls.Config{Certificates: []tls.Certificate{tlsCert}}))
+	srv.Config.ConnContext = ConnContext
+	srv.Start()
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(cert.CA)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	resp, err := client.Get("https://localhost:" + port + "/tls")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	r := <-ch
+	require.NotNil(t, r.TLS)
+	assert.Equal(t, "TLS 1.3", r.TLS.Version)
+	assert.NotEmpty(t, r.TLS.CipherSuite)
+	assert.Equal(t, "localhost", r.TLS.ServerName)
+	assert.Equal(t, "User-Agent", r.Headers[0].Name)
+}
+
+func TestNewRequestWithoutConnection(t *testing.T) {
+	req, _ := http.NewRequest("PUT", "/foo?bar=1", bytes.NewBufferString("xxx"))
+	req.Header.Add("B", "2")
+	req.Header.Add("A", "1")
+	req.Header.Add("B", "3")
+
+	r, err := NewRequest(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/foo?bar=1", r.URL)
+	assert.Equal(t, []HeaderField{{"A", "1"}, {"B", "2"}, {"B", "3"}}, r.Headers)
+	assert.Equal(t, []string{"2", "3"}, r.Header()["B"])
+	assert.Equal(t, "xxx", string(r.Body))
+
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "xxx", string(body))
+}
+
+func TestRequestServedBy(t *testing.T) {
+	rl := NewResponsesList().Add("ok", &Response{Status: 200})
+	r := &Request{}
+	r.ServedBy(&Route{Path: "/foo", Sequence: "seq"}, rl.Get("ok"))
+
+	assert.Equal(t, "* /foo", r.Route)
+	assert.Equal(t, "seq", r.Sequence)
+	assert.Equal(t, "ok", r.Response)
+}
@@ -7,10 +7,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // BodyMode represent the current Body mode
@@ -23,6 +26,10 @@ func (m BodyMode) String() string {
 		return "Input"
 	case BodyFile:
 		return "File"
+	case BodyTemplate:
+		return "Template"
+	case BodySSE:
+		return "SSE"
 	}
 	return ""
 }
@@ -32,6 +39,10 @@ const (
 	BodyInput BodyMode = iota + 1
 	// BodyFile takes  the body input from a file
 	BodyFile
+	// BodyTemplate renders the body input as a text/template, see TemplateContext
+	BodyTemplate
+	// BodySSE keeps the connection open to push Server-Sent Events, the input holds the next event to send
+	BodySSE
 )
 
 // Body is our response body content, that will either reference an local file or a runtime-supplied []byte.
@@ -39,13 +50,17 @@ type Body struct {
 	Mode  BodyMode
 	Input []byte
 	File  *os.File
+	// Events are sent one after the other by BodySSE, once a client connects.
+	Events []Event
 }
 
 // Payload reads out a []byte payload according to it's configuration in Body.BodyMode.
 func (body *Body) Payload() []byte {
 	switch body.Mode {
-	case BodyInput:
+	case BodyInput, BodyTemplate:
 		return body.Input
+	case BodySSE:
+		return eventsPayload(body.Events)
 	case BodyFile:
 		if body.File == nil {
 			return nil
@@ -62,7 +77,7 @@ func (body *Body) Payload() []byte {
 // Info returns some basic info on the body.
 func (body *Body) Info() []byte {
 	switch body.Mode {
-	case BodyInput:
+	case BodyInput, BodyTemplate, BodySSE:
 		return body.Input
 	case BodyFile:
 		if body.File == nil {
@@ -94,10 +109,25 @@ func (body *Body) SetFile(path string) error {
 
 // Response is the the preconfigured HTTP response that will be returned to the client.
 type Response struct {
+	// Name is the key of the response within its ResponsesList, if it belongs to one.
+	Name    string `json:"-"`
 	Status  int
 	Headers http.Header
 	Body    Body
-	Delay   time.Duration
+	Delay   Delay
+	// Throttle, when set, streams the body slowly.
+	Throttle *Throttle `json:",omitempty"`
+	// Fault, when set, breaks the response on purpose.
+	Fault *Fault `json:",omitempty"`
+	// WebSocket, when set, accepts the WebSocket handshake requests get the response for.
+	WebSocket *WebSocket `json:",omitempty"`
+	// Trailers are sent after the body, over chunked HTTP/1.1 or HTTP/2.
+	Trailers http.Header `json:",omitempty"`
+	// Push lists the paths pushed along with the response, when the client accepts HTTP/2 server push.
+	Push []string `json:",omitempty"`
+
+	// placeholders are the values interpolated when the response was loaded, see Interpolate.
+	placeholders *placeholders
 }
 
 // UnmarshalJSON inflates the Response from []byte representing JSON.
@@ -105,9 +135,12 @@ func (r *Response) UnmarshalJSON(data []byte) error {
 	type alias Response
 	v := struct {
 		alias
-		Body    string
-		File    string
-		Headers map[string]string
+		Body     string
+		File     string
+		Template string
+		Events   []Event
+		Headers  map[string]headerValues
+		Trailers map[string]headerValues
 	}{}
 	if err := json.Unmarshal(data, &v); err != nil {
 		return err
@@ -115,24 +148,57 @@ func (r *Response) UnmarshalJSON(data []byte) error {
 
 	r.Status = v.Status
 	r.Delay = v.Delay
-	r.Body.Input = []byte(v.Body)
+	r.Throttle = v.Throttle
+	r.Fault = v.Fault
+	r.WebSocket = v.WebSocket
+	r.Push = v.Push
+	r.placeholders = &placeholders{headers: make(map[string][]placeholder)}
+
+	r.placeholders.body = placeholder{v.Body, Interpolate(v.Body)}
+	r.Body.Input = []byte(r.placeholders.body.value)
 	if v.File != "" {
 		if err := r.Body.SetFile(v.File); err != nil {
 			return err
 		}
+		r.placeholders.file = placeholder{v.File, r.Body.File.Name()}
 	}
 
-	if r.Body.File != nil {
+	switch {
+	case v.Template != "":
+		r.placeholders.body = placeholder{v.Template, Interpolate(v.Template)}
+		r.Body.Input = []byte(r.placeholders.body.value)
+		r.Body.Mode = BodyTemplate
+	case v.Events != nil:
+		r.Body.Events = v.Events
+		r.Body.Mode = BodySSE
+	case r.Body.File != nil:
 		r.Body.Mode = BodyFile
-	} else {
+	default:
 		r.Body.Mode = BodyInput
 	}
 
 	if r.Headers == nil {
 		r.Headers = http.Header{}
 	}
-	for key := range v.Headers {
-		r.Headers.Set(key, v.Headers[key])
+	// keys differing in case only are merged, in a stable order
+	for _, key := range sortedKeys(v.Headers) {
+		values := v.Headers[key]
+		key = http.CanonicalHeaderKey(key)
+		for _, value := range values {
+			p := placeholder{value, Interpolate(value)}
+			r.placeholders.headers[key] = append(r.placeholders.headers[key], p)
+			r.Headers.Add(key, p.value)
+		}
+	}
+
+	r.Trailers = nil
+	for _, key := range sortedKeys(v.Trailers) {
+		if r.Trailers == nil {
+			r.Trailers = http.Header{}
+		}
+		for _, value := range v.Trailers[key] {
+			r.Trailers.Add(key, value)
+		}
 	}
 
 	return nil
@@ -143,31 +209,109 @@ func (r *Response) MarshalJSON() ([]byte, error) {
 	type alias Response
 	v := struct {
 		alias
-		Body    string
-		File    string
-		Headers map[string]string
-	}{
-		Headers: make(map[string]string),
-	}
+		Body     string
+		File     string
+		Template string   `json:",omitempty"`
+		Events   *[]Event `json:",omitempty"`
+		Headers  map[string]headerValues
+		Trailers map[string]headerValues `json:",omitempty"`
+	}{}
 
-	v.Delay = time.Duration(r.Delay) / time.Millisecond
+	v.Delay = r.Delay
 	v.Status = r.Status
+	v.Throttle = r.Throttle
+	v.Fault = r.Fault
+	v.WebSocket = r.WebSocket
+	v.Push = r.Push
+
+	// the placeholders are written back while the values they were interpolated into aren't edited
+	p := r.placeholders
+	if p == nil {
+		p = &placeholders{}
+	}
 
-	if len(r.Body.Input) > 0 {
-		v.Body = string(r.Body.Input)
+	if r.Body.Mode == BodyTemplate {
+		v.Template = p.body.restore(string(r.Body.Input))
+	} else if len(r.Body.Input) > 0 {
+		v.Body = p.body.restore(string(r.Body.Input))
+	}
+
+	if r.Body.Mode == BodySSE {
+		events := append([]Event{}, r.Body.Events...)
+		v.Events = &events
 	}
 
 	if r.Body.File != nil {
-		v.File = r.Body.File.Name()
+		v.File = p.file.restore(r.Body.File.Name())
 	}
 
-	for key := range r.Headers {
-		v.Headers[key] = r.Headers.Get(key)
+	v.Headers = p.restoreHeaders(r.Headers)
+
+	for key, values := range r.Trailers {
+		if v.Trailers == nil {
+			v.Trailers = make(map[string]headerValues)
+		}
+		v.Trailers[key] = values
 	}
 
 	return json.MarshalIndent(v, "", "  ")
 }
 
+// headerValues are the values of a header in the config,
+// a string when there's a single one and an array otherwise.
+type headerValues []string
+
+// MarshalJSON writes a single value as a string.
+func (h headerValues) MarshalJSON() ([]byte, error) {
+	if len(h) == 1 {
+		return json.Marshal(h[0])
+	}
+	return json.Marshal([]string(h))
+}
+
+// UnmarshalJSON reads either a string or an array of strings.
+func (h *headerValues) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*h = headerValues{value}
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("header values should be a string or an array of strings")
+	}
+	*h = values
+	return nil
+}
+
+// sortedKeys returns the keys of the headers read from a config, sorted.
+func sortedKeys(hdr map[string]headerValues) []string {
+	keys := make([]string, 0, len(hdr))
+	for key := range hdr {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatHeaders returns hdr as "Key: value" lines sorted by key, with a line per value.
+func FormatHeaders(hdr http.Header) string {
+	keys := make([]string, 0, len(hdr))
+	for key := range hdr {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+	for _, key := range keys {
+		for _, value := range hdr[key] {
+			fmt.Fprintf(&buf, "%s: %s\n", key, value)
+		}
+	}
+	return buf.String()
+}
+
 // NewResponse configures a new response. An empty status will be interpreted as 200 OK.
 func NewResponse(status, headers, body string) (*Response, error) {
 	// Parse Status
@@ -198,7 +342,7 @@ func NewResponse(status, headers, body string) (*Response, error) {
 		}
 		key := strings.TrimSpace(kv[0])
 		val := strings.TrimSpace(kv[1])
-		hdr.Set(key, val)
+		hdr.Add(key, val)
 	}
 
 	return &Response{
@@ -213,20 +357,133 @@ func NewResponse(status, headers, body string) (*Response, error) {
 
 // Write flushes the body into the ResponseWriter, hence sending it over the wire.
 func (r *Response) Write(w http.ResponseWriter) error {
-	for key := range r.Headers {
-		w.Header().Set(key, r.Headers.Get(key))
+	r.setHeaders(w)
+	pushErr := r.push(w)
+	r.declareTrailers(w)
+
+	var err error
+	if r.Throttle != nil {
+		err = r.writeThrottled(w)
+	} else {
+		w.WriteHeader(r.Status)
+		_, err = w.Write(r.Body.Payload())
+	}
+	if err != nil {
+		return err
 	}
-	w.WriteHeader(r.Status)
-	_, err := w.Write(r.Body.Payload())
 
-	return err
+	r.setTrailers(w)
+	return pushErr
 }
 
-// ResponsesList holds the multiple configured responses.
+// push pushes the paths listed on r.Push, nothing is pushed when the client doesn't accept it.
+func (r *Response) push(w http.ResponseWriter) error {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return nil
+	}
+
+	for _, target := range r.Push {
+		if err := pusher.Push(target, nil); err != nil {
+			if err == http.ErrNotSupported {
+				return nil
+			}
+			return fmt.Errorf("Push %s: %v", target, err)
+		}
+	}
+	return nil
+}
+
+// declareTrailers announces the trailers of r, which have to be declared before the headers are sent.
+// A Trailer header set by hand is left as it is when r has no trailers of its own.
+func (r *Response) declareTrailers(w http.ResponseWriter) {
+	if len(r.Trailers) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(r.Trailers))
+	for key := range r.Trailers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.Header().Del("Trailer")
+	for _, key := range keys {
+		w.Header().Add("Trailer", key)
+	}
+}
+
+// setTrailers sets the trailers of r into w, once the body has been written.
+func (r *Response) setTrailers(w http.ResponseWriter) {
+	for key, values := range r.Trailers {
+		w.Header().Del(key)
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+}
+
+// setHeaders sets the headers of r into w.
+func (r *Response) setHeaders(w http.ResponseWriter) {
+	for key, values := range r.Headers {
+		w.Header().Del(key)
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+}
+
+// Serve renders the response for req, waits for its Delay and writes it into w.
+// If the response can't be rendered, an Internal Server Error is sent and the error returned.
+// When its Fault triggers, the fault is injected instead and reported as an error.
+// WebSocket handshakes are accepted when the response has a WebSocket section, and SSE bodies are streamed,
+// by the Brokers of the server, see Brokers.BaseContext.
+func (r *Response) Serve(w http.ResponseWriter, req *http.Request) error {
+	resp, err := r.Render(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return fmt.Errorf("Template error: %v", err)
+	}
+
+	time.Sleep(resp.Delay.Duration())
+	if resp.Fault != nil && resp.Fault.Triggers() {
+		resp.setHeaders(w)
+		return resp.Fault.Inject(w, req, resp)
+	}
+	if resp.WebSocket != nil && websocket.IsWebSocketUpgrade(req) {
+		return brokersFromContext(req.Context()).Sockets.Serve(w, req, resp)
+	}
+	if resp.Body.Mode == BodySSE {
+		resp.setHeaders(w)
+		return brokersFromContext(req.Context()).Events.Serve(w, req, resp)
+	}
+	return resp.Write(w)
+}
+
+// ResponsesList holds the multiple configured responses and the routes serving them.
+// They can come from several config files, see Load.
 type ResponsesList struct {
-	List    map[string]*Response
-	keys    []string
-	current int
+	List      map[string]*Response
+	Routes    []*Route
+	Sequences map[string]*Sequence
+	keys      []string
+	current   int
+	// files are the config files as last loaded or saved, from the lowest precedence to the highest.
+	files []*diskState
+	// primary is the file new entries are saved into, see Save.
+	primary string
+	// origins map the responses and sequences to the file they come from, missing ones weren't saved yet.
+	origins         map[string]string
+	sequenceOrigins map[string]string
+}
+
+// config is the on-disk representation of a ResponsesList.
+type config struct {
+	// Include lists other config files, or glob patterns matching them, relative to this one.
+	Include   []string `json:",omitempty"`
+	Responses map[string]*Response
+	Routes    []*Route             `json:",omitempty"`
+	Sequences map[string]*Sequence `json:",omitempty"`
 }
 
 // NewResponsesList creates a new empty response list and returns it.
@@ -237,79 +494,243 @@ func NewResponsesList() *ResponsesList {
 func (rl *ResponsesList) reset() *ResponsesList {
 	rl.current = 0
 	rl.List = make(map[string]*Response)
+	rl.Routes = nil
+	rl.Sequences = make(map[string]*Sequence)
 	rl.keys = nil
+	rl.files = nil
+	rl.origins = make(map[string]string)
+	rl.sequenceOrigins = make(map[string]string)
 	return rl
 }
 
-func (rl *ResponsesList) load(path string) (map[string]*Response, error) {
-	f, err := openConfigFile(path)
+// loadConfig reads the config file at path, read as YAML or TOML when path has a .yaml, .yml or .toml extension.
+func loadConfig(path string) (*config, os.FileInfo, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer f.Close()
 
-	rs := struct {
-		Responses map[string]*Response
-	}{}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if err := json.NewDecoder(f).Decode(&rs); err != nil {
-		if err == io.EOF {
-			return nil, nil
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var c config
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &c, info, nil
+	}
+	if err := ConfigFormatFor(path).unmarshal(data, &c); err != nil {
+		return nil, nil, err
+	}
+
+	for _, route := range c.Routes {
+		if err := route.compile(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for name, seq := range c.Sequences {
+		if err := seq.validate(); err != nil {
+			return nil, nil, fmt.Errorf("sequence %s: %v", name, err)
+		}
+	}
+
+	return &c, info, nil
+}
+
+// loadConfigs reads the config files at paths, created if missing, along with the ones they include, from the lowest precedence
+// to the highest: every file comes right after the files it includes. A file is only read once.
+func loadConfigs(paths []string) ([]*diskState, error) {
+	var files []*diskState
+	seen := make(map[string]bool)
+
+	var visit func(path string) error
+	visit = func(path string) error {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if seen[abs] {
+			return nil
+		}
+		seen[abs] = true
+
+		c, info, err := loadConfig(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+
+		for _, pattern := range c.Include {
+			matches, err := includedPaths(path, pattern)
+			if err != nil {
+				return err
+			}
+			for _, match := range matches {
+				if err := visit(match); err != nil {
+					return err
+				}
+			}
+		}
+
+		file := &diskState{}
+		if err := file.set(path, info, c); err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	}
+
+	for _, path := range paths {
+		if err := createConfigFile(path); err != nil {
+			return nil, err
+		}
+		if err := visit(path); err != nil {
+			return nil, err
 		}
-		return nil, err
+	}
+	return files, nil
+}
+
+// includedPaths returns the files matched by pattern, an Include of the config file from.
+func includedPaths(from, pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	pattern = ExpandPath(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
 	}
 
-	return rs.Responses, nil
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %v", pattern, err)
+	}
+	// only patterns are allowed to match nothing
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		return nil, fmt.Errorf("include %s: no such file", pattern)
+	}
+	return matches, nil
 }
 
-// Load loads a response list from a local JSON document.
-func (rl *ResponsesList) Load(path string) error {
-	rs, err := rl.load(path)
+// Load loads a response list from local documents, read as YAML or TOML when their path has
+// a .yaml, .yml or .toml extension, JSON otherwise.
+// Every file overlays the ones given before it, and the files it includes: its responses and sequences
+// replace the ones with the same name, and its routes are matched first.
+// Paths which don't exist are created, the last one is where new entries are saved, see Save.
+func (rl *ResponsesList) Load(paths ...string) error {
+	files, err := loadConfigs(paths)
 	if err != nil {
 		return err
 	}
 
+	seqs := rl.Sequences
 	rl.reset()
-	if rs != nil {
-		rl.List = rs
+	rl.files = files
+	if len(paths) > 0 {
+		rl.primary = paths[len(paths)-1]
+	}
+
+	for _, file := range files {
+		for key, resp := range file.config.Responses {
+			resp.Name = key
+			rl.List[key] = resp
+			rl.origins[key] = file.path
+		}
+
+		// keep the position of the sequences that were already loaded
+		for name, seq := range file.config.Sequences {
+			if old, ok := seqs[name]; ok {
+				seq.counter = old.Position()
+			}
+			rl.Sequences[name] = seq
+			rl.sequenceOrigins[name] = file.path
+		}
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		for _, route := range files[i].config.Routes {
+			route.origin = files[i].path
+			rl.Routes = append(rl.Routes, route)
+		}
 	}
 
-	for key := range rs {
+	for key := range rl.List {
 		rl.keys = append(rl.keys, key)
 	}
 	sort.Strings(rl.keys)
-
 	return nil
 }
 
-// Save saves the current response list to a JSON document on local disk.
-func (rl *ResponsesList) Save(path string) error {
-	f, err := openConfigFile(path)
-	if err != nil {
-		return err
+// Files returns the config files the list was loaded from or saved into, from the lowest precedence to the highest.
+func (rl *ResponsesList) Files() []string {
+	paths := make([]string, len(rl.files))
+	for i, file := range rl.files {
+		paths[i] = file.path
 	}
-	defer f.Close()
+	return paths
+}
 
-	stat, err := f.Stat()
-	if err != nil {
-		return err
-	}
+// Origin returns the config file the response name comes from, it's empty when it wasn't saved yet.
+func (rl *ResponsesList) Origin(name string) string { return rl.origins[name] }
 
-	if err := f.Truncate(stat.Size()); err != nil {
-		return err
+// file returns the state of the config file at path, nil if it wasn't loaded nor saved.
+func (rl *ResponsesList) file(path string) *diskState {
+	for _, file := range rl.files {
+		if file.path == path {
+			return file
+		}
 	}
+	return nil
+}
 
-	buf, err := json.MarshalIndent(struct {
-		Responses map[string]*Response
-	}{rl.List}, "", "  ")
-	if err != nil {
-		return err
+// rank returns the precedence of the config file at path, the files which weren't loaded come last.
+func (rl *ResponsesList) rank(path string) int {
+	for i, file := range rl.files {
+		if file.path == path {
+			return i
+		}
 	}
+	return len(rl.files)
+}
 
-	if _, err := f.Write(buf); err != nil {
-		return err
+// Match returns the first route matching req together with the response it points to.
+// If the route serves a sequence, the sequence is advanced.
+// Routes pointing to a response or sequence that doesn't exist are skipped.
+// It returns nil values when no route matches.
+func (rl *ResponsesList) Match(req *http.Request) (*Route, *Response) {
+	for _, route := range rl.Routes {
+		if !route.Match(req) {
+			continue
+		}
+
+		name := route.Response
+		if route.Sequence != "" {
+			seq, ok := rl.Sequences[route.Sequence]
+			if !ok {
+				continue
+			}
+			name = seq.Next()
+		}
+
+		if resp, ok := rl.List[name]; ok {
+			return route, resp
+		}
 	}
+	return nil, nil
+}
 
-	return nil
+// ResetSequences starts every sequence over.
+func (rl *ResponsesList) ResetSequences() {
+	for _, seq := range rl.Sequences {
+		seq.Reset()
+	}
 }
 
 // Next iterates to the next item in the response list.
@@ -335,33 +756,61 @@ func (rl *ResponsesList) Get(key string) *Response { return rl.List[key] }
 
 // Add appends a response item to the list. You need to supply a key for the item.
 func (rl *ResponsesList) Add(key string, r *Response) *ResponsesList {
-	rl.keys = append(rl.keys, key)
-	sort.Strings(rl.keys)
+	if _, ok := rl.List[key]; !ok {
+		rl.keys = append(rl.keys, key)
+		sort.Strings(rl.keys)
+	}
+	r.Name = key
 	rl.List[key] = r
 	return rl
 }
 
 // Del removes an item spceified by its key from the response list. It returns false if the item didn't exist at all.
+// When it was overlaying a response with the same name, from a file of lower precedence, that one takes its place.
 func (rl *ResponsesList) Del(key string) bool {
 	if _, ok := rl.List[key]; !ok {
 		return false
 	}
 	delete(rl.List, key)
+	origin, saved := rl.origins[key]
+	delete(rl.origins, key)
 
 	i := sort.SearchStrings(rl.keys, key)
 	rl.keys = append(rl.keys[:i], rl.keys[i+1:]...)
 
+	if !saved {
+		return true
+	}
+
+	// the response it was overlaying shows up again
+	for i := rl.rank(origin) - 1; i >= 0; i-- {
+		if resp, ok := rl.files[i].config.Responses[key]; ok {
+			rl.Add(key, resp)
+			rl.origins[key] = rl.files[i].path
+			break
+		}
+	}
 	return true
 }
 
 // ExpandPath expands a given path by replacing '~' with $HOME of the current user.
+// Variables are expanded like $VAR, ${VAR} or ${VAR:-default}, looked up with LookupEnv.
 func ExpandPath(path string) string {
 	if path[0] == '~' {
 		path = "$HOME" + path[1:]
 	}
-	return os.ExpandEnv(path)
+	return os.Expand(path, expandVar)
 }
 
-func openConfigFile(path string) (*os.File, error) {
-	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+// createConfigFile creates an empty config file at path, unless it exists.
+func createConfigFile(path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
 }
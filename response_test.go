@@ -1,10 +1,12 @@
 package httplab
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -57,6 +59,42 @@ func TestResponseHeaders(t *testing.T) {
 	assert.Equal(t, "http://foo.bar:8000", resp.Headers.Get("Location"))
 	assert.Contains(t, resp.Headers, "X-Empty")
 	assert.NotContains(t, resp.Headers, "Invalid")
+
+	t.Run("Repeated lines", func(t *testing.T) {
+		resp, err := NewResponse("", "Set-Cookie: a=1\nVary: Accept\nset-cookie: b=2\nSet-Cookie: c=3", "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a=1", "b=2", "c=3"}, resp.Headers["Set-Cookie"])
+		assert.Equal(t, "Set-Cookie: a=1\nSet-Cookie: b=2\nSet-Cookie: c=3\nVary: Accept\n", FormatHeaders(resp.Headers))
+	})
+}
+
+func TestResponseHeadersJSON(t *testing.T) {
+	resp := &Response{
+		Status: 200,
+		Headers: http.Header{
+			"Link":         {"</a>; rel=next", "</b>; rel=prev"},
+			"Content-Type": {"text/plain"},
+		},
+		Body: Body{Mode: BodyInput},
+	}
+
+	buf, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), `"Content-Type":"text/plain"`)
+	assert.Contains(t, string(buf), `"Link":["\u003c/a\u003e; rel=next","\u003c/b\u003e; rel=prev"]`)
+
+	var loaded Response
+	require.NoError(t, json.Unmarshal(buf, &loaded))
+	assert.Equal(t, resp.Headers, loaded.Headers)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"Headers": {"X-Foo": 1}}`), &loaded))
+
+	// keys differing in case only are merged in the same order on every load
+	for i := 0; i < 10; i++ {
+		var cased Response
+		require.NoError(t, json.Unmarshal([]byte(`{"Headers": {"x-foo": ["c", "d"], "X-Foo": "a", "X-FOO": "b"}}`), &cased))
+		assert.Equal(t, []string{"b", "a", "c", "d"}, cased.Headers["X-Foo"])
+	}
 }
 
 func TestResponseWrite(t *testing.T) {
@@ -71,10 +109,13 @@ func TestResponseWrite(t *testing.T) {
 		},
 	}
 
+	resp.Headers["Set-Cookie"] = []string{"a=1", "b=2"}
+	rec.Header().Set("Set-Cookie", "replaced=1")
 	resp.Write(rec)
 
 	assert.Equal(t, resp.Status, rec.Code)
 	assert.Equal(t, resp.Headers.Get("X-Foo"), rec.Header().Get("X-Foo"))
+	assert.Equal(t, []string{"a=1", "b=2"}, rec.Header()["Set-Cookie"])
 	assert.Equal(t, resp.Body.Payload(), rec.Body.Bytes())
 }
 
@@ -141,8 +182,10 @@ func TestLoadFromJSON(t *testing.T) {
 	r := rl.Get("t1")
 	require.NotNil(t, r)
 	assert.Equal(t, 200, r.Status)
-	assert.Equal(t, time.Duration(1000), r.Delay)
+	// legacy numbers are milliseconds
+	assert.Equal(t, FixedDelay(time.Second), r.Delay)
 	assert.Equal(t, "value", r.Headers.Get("X-MyHeader"))
+	assert.Equal(t, []string{"a=1; Path=/", "b=2; Path=/"}, r.Headers["Set-Cookie"])
 
 	r.Body.Mode = BodyInput
 	assert.Equal(t, []byte("xxx"), r.Body.Payload())
@@ -152,7 +195,7 @@ func TestLoadFromJSON(t *testing.T) {
 	assert.Equal(t, []byte("<html></html>"), r.Body.Payload())
 
 	t.Run("When config file is empty", func(t *testing.T) {
-		path := string(time.Now().UnixNano())
+		path := strconv.FormatInt(time.Now().UnixNano(), 10)
 		defer os.Remove(path)
 
 		require.NoError(t, rl.Load(path))
@@ -164,6 +207,81 @@ func TestLoadFromJSON(t *testing.T) {
 	})
 }
 
+// writeConfigs writes the config files, keyed by their path relative to dir.
+func writeConfigs(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigs(t, dir, map[string]string{
+		"system": `{
+			"Responses": {"a": {"Status": 200}, "b": {"Status": 200}},
+			"Routes": [{"Path": "/system", "Response": "a"}]
+		}`,
+		"user": `{
+			"Responses": {"b": {"Status": 201}, "c": {"Status": 200}},
+			"Sequences": {"s": {"Responses": ["a", "b"]}},
+			"Routes": [{"Path": "/user", "Response": "b"}]
+		}`,
+		"project/.httplab": `{
+			"Include": ["lib/*.json", "lib/*.yaml"],
+			"Responses": {"c": {"Status": 202}},
+			"Routes": [{"Path": "/project", "Response": "c"}]
+		}`,
+		"project/lib/one.json": `{
+			"Include": ["../.httplab"],
+			"Responses": {"a": {"Status": 203}, "d": {"Status": 200}},
+			"Routes": [{"Path": "/lib", "Response": "d"}]
+		}`,
+		"project/lib/two.yaml": "Responses:\n  e:\n    Status: 200\n",
+	})
+	system, user := filepath.Join(dir, "system"), filepath.Join(dir, "user")
+	project := filepath.Join(dir, "project", ".httplab")
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(system, user, project))
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, rl.Keys())
+	assert.Equal(t, []string{
+		system, user, filepath.Join(dir, "project", "lib", "one.json"), filepath.Join(dir, "project", "lib", "two.yaml"), project,
+	}, rl.Files())
+
+	for name, expected := range map[string]struct {
+		status int
+		origin string
+	}{
+		"a": {203, filepath.Join(dir, "project", "lib", "one.json")},
+		"b": {201, user},
+		"c": {202, project},
+		"e": {200, filepath.Join(dir, "project", "lib", "two.yaml")},
+	} {
+		assert.Equal(t, expected.status, rl.Get(name).Status, name)
+		assert.Equal(t, expected.origin, rl.Origin(name), name)
+	}
+
+	var paths []string
+	for _, route := range rl.Routes {
+		paths = append(paths, route.Path)
+	}
+	assert.Equal(t, []string{"/project", "/lib", "/user", "/system"}, paths)
+	assert.Contains(t, rl.Sequences, "s")
+
+	// deleting a response brings back the one it was overlaying
+	rl.Del("a")
+	assert.Equal(t, 200, rl.Get("a").Status)
+	assert.Equal(t, system, rl.Origin("a"))
+	rl.Del("d")
+	assert.Nil(t, rl.Get("d"))
+
+	writeConfigs(t, dir, map[string]string{"broken": `{"Include": ["missing.json"]}`})
+	err := NewResponsesList().Load(filepath.Join(dir, "broken"))
+	assert.EqualError(t, err, "include "+filepath.Join(dir, "missing.json")+": no such file")
+}
+
 func TestExpandPathExpansion(t *testing.T) {
 	defer os.Setenv("HOME", os.Getenv("HOME"))
 
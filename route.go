@@ -0,0 +1,113 @@
+package httplab
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Route maps the requests matching all of its criteria to a saved response.
+// Empty criteria match any request.
+type Route struct {
+	// Name is an optional label used to identify the route.
+	Name string `json:",omitempty"`
+	// Method matches the request method, case insensitively.
+	Method string `json:",omitempty"`
+	// Path is a glob pattern (see path.Match) the request path has to match.
+	Path string `json:",omitempty"`
+	// Regexp is a regular expression the request path has to match.
+	Regexp string `json:",omitempty"`
+	// Query holds glob patterns that the query parameters have to match.
+	Query map[string]string `json:",omitempty"`
+	// Headers holds glob patterns that the request headers have to match.
+	Headers map[string]string `json:",omitempty"`
+	// Response is the name of the response to be served.
+	Response string `json:",omitempty"`
+	// Sequence is the name of the sequence serving the responses, it takes precedence over Response.
+	Sequence string `json:",omitempty"`
+
+	regexp *regexp.Regexp
+	// compiled makes routes built in code compile their Regexp once, as requests are matched concurrently.
+	compiled sync.Once
+	// origin is the config file the route comes from, empty when it wasn't saved yet.
+	origin string
+}
+
+func (r *Route) compile() error {
+	if r.Path != "" {
+		if _, err := path.Match(r.Path, "/"); err != nil {
+			return fmt.Errorf("route %s: %v", r, err)
+		}
+	}
+
+	if r.Regexp != "" {
+		re, err := regexp.Compile(r.Regexp)
+		if err != nil {
+			return fmt.Errorf("route %s: %v", r, err)
+		}
+		r.regexp = re
+	}
+
+	return nil
+}
+
+// Match reports whether the request satisfies every criteria of the route.
+func (r *Route) Match(req *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+
+	if r.Path != "" && !globMatch(r.Path, req.URL.Path) {
+		return false
+	}
+
+	if r.Regexp != "" {
+		r.compiled.Do(func() {
+			if r.regexp == nil {
+				r.regexp, _ = regexp.Compile(r.Regexp)
+			}
+		})
+		if r.regexp == nil || !r.regexp.MatchString(req.URL.Path) {
+			return false
+		}
+	}
+
+	query := req.URL.Query()
+	for key, pattern := range r.Query {
+		if _, ok := query[key]; !ok || !globMatch(pattern, query.Get(key)) {
+			return false
+		}
+	}
+
+	for key, pattern := range r.Headers {
+		if _, ok := req.Header[http.CanonicalHeaderKey(key)]; !ok || !globMatch(pattern, req.Header.Get(key)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the route name, or a description of its criteria if it has none.
+func (r *Route) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	method := valueOrDefault(strings.ToUpper(r.Method), "*")
+	switch {
+	case r.Path != "":
+		return method + " " + r.Path
+	case r.Regexp != "":
+		return method + " ~" + r.Regexp
+	}
+	return method + " *"
+}
+
+func globMatch(pattern, value string) bool {
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
@@ -0,0 +1,78 @@
+package httplab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsesListMatch(t *testing.T) {
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load("./testdata/routes.json"))
+	require.Len(t, rl.Routes, 4)
+
+	tests := []struct {
+		method   string
+		url      string
+		headers  map[string]string
+		route    string
+		response int
+	}{
+		{"GET", "/users/1", nil, "GET /users/*", 200},
+		{"GET", "/users/1", map[string]string{"X-Role": "administrator"}, "admin", 403},
+		{"DELETE", "/users/1", nil, "", 0},
+		{"GET", "/users/1/posts", nil, "", 0},
+		{"POST", "/users?dry=false", nil, "POST ~^/users/?$", 201},
+		{"POST", "/users/?dry=false", nil, "POST ~^/users/?$", 201},
+		{"POST", "/users", nil, "", 0},
+		{"GET", "/missing", nil, "", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.url, func(t *testing.T) {
+			req, _ := http.NewRequest(test.method, test.url, nil)
+			for key, val := range test.headers {
+				req.Header.Set(key, val)
+			}
+
+			route, resp := rl.Match(req)
+			if test.route == "" {
+				assert.Nil(t, route)
+				assert.Nil(t, resp)
+				return
+			}
+
+			require.NotNil(t, route)
+			assert.Equal(t, test.route, route.String())
+			assert.Equal(t, test.response, resp.Status)
+		})
+	}
+}
+
+func TestLoadInvalidRoute(t *testing.T) {
+	path := t.TempDir() + "/httplab.json"
+	rl := NewResponsesList()
+	rl.Routes = []*Route{{Regexp: "(", Response: "x"}}
+	require.NoError(t, rl.Save(path))
+
+	assert.Error(t, NewResponsesList().Load(path))
+}
+
+func TestRouteMatchConcurrently(t *testing.T) {
+	route := &Route{Regexp: `^/users/\d+$`}
+	req := httptest.NewRequest("GET", "/users/42", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, route.Match(req))
+		}()
+	}
+	wg.Wait()
+}
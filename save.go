@@ -0,0 +1,453 @@
+package httplab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ConflictError is returned by Save when the config file was modified since the list was loaded or saved,
+// by another HTTPLab instance for example. Merge takes those changes in, Overwrite discards them.
+type ConflictError struct {
+	Path string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s was modified since it was loaded", e.Path)
+}
+
+// snapshot holds the JSON of every entry of a config, to tell which ones changed.
+type snapshot struct {
+	responses map[string][]byte
+	sequences map[string][]byte
+	routes    []byte
+}
+
+func takeSnapshot(c *config) (snapshot, error) {
+	s := snapshot{
+		responses: make(map[string][]byte),
+		sequences: make(map[string][]byte),
+	}
+
+	for name, resp := range c.Responses {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return s, err
+		}
+		s.responses[name] = data
+	}
+
+	for name, seq := range c.Sequences {
+		data, err := json.Marshal(seq)
+		if err != nil {
+			return s, err
+		}
+		s.sequences[name] = data
+	}
+
+	var err error
+	s.routes, err = json.Marshal(c.Routes)
+	return s, err
+}
+
+// diskState is what a ResponsesList knows about one of its config files.
+type diskState struct {
+	path    string
+	modTime time.Time
+	size    int64
+	// config is the content of the file, and snapshot its JSON: the base changes are merged from.
+	config *config
+	snapshot
+}
+
+func (d *diskState) set(path string, info os.FileInfo, c *config) error {
+	s, err := takeSnapshot(c)
+	if err != nil {
+		return err
+	}
+
+	*d = diskState{path: path, modTime: info.ModTime(), size: info.Size(), config: c, snapshot: s}
+	return nil
+}
+
+// changed reports whether the file at path was modified since it was loaded or saved.
+// A file which was never loaded nor saved has changed unless it's empty.
+func (d *diskState) changed(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if d.path != path {
+		return info.Size() > 0, nil
+	}
+	return !info.ModTime().Equal(d.modTime) || info.Size() != d.size, nil
+}
+
+// target returns the file an entry coming from origin is saved into, the primary one when it wasn't saved yet.
+func (rl *ResponsesList) target(origin string) string {
+	if origin == "" {
+		return rl.primary
+	}
+	return origin
+}
+
+// overlaid reports whether an entry coming from origin overlays the entries with the same name of path.
+func (rl *ResponsesList) overlaid(origin, path string) bool {
+	return rl.rank(rl.target(origin)) > rl.rank(path)
+}
+
+// fileConfig returns what the config file at path holds: the entries coming from it, along with the ones
+// it had which are overlaid by a file of higher precedence.
+func (rl *ResponsesList) fileConfig(path string) *config {
+	c := &config{Responses: make(map[string]*Response)}
+	for name, resp := range rl.List {
+		if rl.target(rl.origins[name]) == path {
+			c.Responses[name] = resp
+		}
+	}
+	for name, seq := range rl.Sequences {
+		if rl.target(rl.sequenceOrigins[name]) == path {
+			if c.Sequences == nil {
+				c.Sequences = make(map[string]*Sequence)
+			}
+			c.Sequences[name] = seq
+		}
+	}
+	for _, route := range rl.Routes {
+		if rl.target(route.origin) == path {
+			c.Routes = append(c.Routes, route)
+		}
+	}
+
+	file := rl.file(path)
+	if file == nil {
+		return c
+	}
+
+	c.Include = file.config.Include
+	for name, resp := range file.config.Responses {
+		if _, ok := rl.List[name]; ok && rl.overlaid(rl.origins[name], path) {
+			c.Responses[name] = resp
+		}
+	}
+	for name, seq := range file.config.Sequences {
+		if _, ok := rl.Sequences[name]; ok && rl.overlaid(rl.sequenceOrigins[name], path) {
+			if c.Sequences == nil {
+				c.Sequences = make(map[string]*Sequence)
+			}
+			c.Sequences[name] = seq
+		}
+	}
+	return c
+}
+
+// pendingWrite is a config file to be written, with its new content.
+type pendingWrite struct {
+	path   string
+	config *config
+}
+
+// pending returns path, the primary file, along with the config files whose content changed since they were
+// loaded or saved, and the ones modified on disk meanwhile when overwrite is set.
+func (rl *ResponsesList) pending(path string, overwrite bool) ([]pendingWrite, error) {
+	rl.primary = path
+
+	var writes []pendingWrite
+	for _, file := range rl.files {
+		c := rl.fileConfig(file.path)
+		s, err := takeSnapshot(c)
+		if err != nil {
+			return nil, err
+		}
+
+		write := file.path == path || !diffConfig(file.snapshot, s).Empty()
+		if !write && overwrite {
+			if write, err = file.changed(file.path); err != nil {
+				return nil, err
+			}
+		}
+		if write {
+			writes = append(writes, pendingWrite{file.path, c})
+		}
+	}
+
+	if rl.file(path) == nil {
+		writes = append(writes, pendingWrite{path, rl.fileConfig(path)})
+	}
+	return writes, nil
+}
+
+// write writes the pending config files, the entries which weren't saved yet belong to them afterwards.
+func (rl *ResponsesList) write(writes []pendingWrite) error {
+	for _, w := range writes {
+		info, err := writeConfig(w.path, w.config)
+		if err != nil {
+			return err
+		}
+
+		file := rl.file(w.path)
+		if file == nil {
+			file = &diskState{}
+			rl.files = append(rl.files, file)
+		}
+		if err := file.set(w.path, info, w.config); err != nil {
+			return err
+		}
+
+		for name := range w.config.Responses {
+			if _, ok := rl.origins[name]; !ok {
+				rl.origins[name] = w.path
+			}
+		}
+		for name := range w.config.Sequences {
+			if _, ok := rl.sequenceOrigins[name]; !ok {
+				rl.sequenceOrigins[name] = w.path
+			}
+		}
+		for _, route := range w.config.Routes {
+			if route.origin == "" {
+				route.origin = w.path
+			}
+		}
+	}
+	return nil
+}
+
+// Save saves the response list to documents on local disk, written as YAML or TOML
+// when their path has a .yaml, .yml or .toml extension, JSON otherwise.
+// Every entry goes back to the config file it was loaded from, the new ones are saved into path.
+// Besides path, only the files whose content changed are written.
+// A document is written into a temporary file renamed into place, so it's never left half-written,
+// and its previous version is kept with a .bak extension.
+// It returns a *ConflictError, without saving, when one of them was modified since it was loaded or saved.
+func (rl *ResponsesList) Save(path string) error {
+	writes, err := rl.pending(path, false)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range writes {
+		var file diskState
+		if f := rl.file(w.path); f != nil {
+			file = *f
+		}
+
+		changed, err := file.changed(w.path)
+		if err != nil {
+			return err
+		}
+		if changed {
+			return &ConflictError{Path: w.path}
+		}
+	}
+
+	return rl.write(writes)
+}
+
+// Overwrite saves the response list like Save, discarding the changes made to the config files meanwhile.
+func (rl *ResponsesList) Overwrite(path string) error {
+	writes, err := rl.pending(path, true)
+	if err != nil {
+		return err
+	}
+	return rl.write(writes)
+}
+
+// writeConfig writes c into path, keeping its previous version as path.bak.
+func writeConfig(path string, c *config) (os.FileInfo, error) {
+	buf, err := ConfigFormatFor(path).marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// symlinks are kept, their target is replaced instead
+	target := path
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		target = resolved
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode().Perm()
+
+		old, err := os.ReadFile(target)
+		if err != nil {
+			return nil, err
+		}
+		if len(old) > 0 {
+			if err := writeFileAtomic(target+".bak", old, mode); err != nil {
+				return nil, err
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := writeFileAtomic(target, buf, mode); err != nil {
+		return nil, err
+	}
+	return os.Stat(target)
+}
+
+// SaveMerging saves the response list like Save, merging the changes made to the config files meanwhile first.
+// It returns the names of the entries taken from them, see Merge.
+func (rl *ResponsesList) SaveMerging(path string) ([]string, error) {
+	var merged []string
+	// every file is merged once at most
+	for i := 0; i <= len(rl.files); i++ {
+		err := rl.Save(path)
+		conflict, ok := err.(*ConflictError)
+		if !ok {
+			return merged, err
+		}
+
+		names, err := rl.Merge(conflict.Path)
+		if err != nil {
+			return merged, err
+		}
+		merged = append(merged, names...)
+	}
+	return merged, rl.Save(path)
+}
+
+// Merge takes in the changes made to the config file at path since the list was loaded or saved:
+// the responses, sequences and routes changed there but not on the list are taken from it,
+// the list wins when both changed them. Save can be called afterwards.
+// It returns the names of the entries taken from path.
+func (rl *ResponsesList) Merge(path string) ([]string, error) {
+	c, info, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	theirs, err := takeSnapshot(c)
+	if err != nil {
+		return nil, err
+	}
+	mine, err := takeSnapshot(rl.fileConfig(path))
+	if err != nil {
+		return nil, err
+	}
+
+	file := rl.file(path)
+	var base snapshot
+	if file != nil {
+		base = file.snapshot
+	}
+
+	// the entries overlaid by another file only change on path
+	var merged []string
+	for _, name := range theirChanges(base.responses, mine.responses, theirs.responses) {
+		_, exists := rl.List[name]
+		if resp, ok := c.Responses[name]; ok && !(exists && rl.overlaid(rl.origins[name], path)) {
+			rl.Add(name, resp)
+			rl.origins[name] = path
+		} else if !ok && exists && rl.target(rl.origins[name]) == path {
+			rl.Del(name)
+		}
+		merged = append(merged, name)
+	}
+
+	for _, name := range theirChanges(base.sequences, mine.sequences, theirs.sequences) {
+		_, exists := rl.Sequences[name]
+		if seq, ok := c.Sequences[name]; ok && !(exists && rl.overlaid(rl.sequenceOrigins[name], path)) {
+			rl.Sequences[name] = seq
+			rl.sequenceOrigins[name] = path
+		} else if !ok && exists && rl.target(rl.sequenceOrigins[name]) == path {
+			delete(rl.Sequences, name)
+			delete(rl.sequenceOrigins, name)
+		}
+		merged = append(merged, "sequence "+name)
+	}
+
+	if bytes.Equal(mine.routes, base.routes) && !bytes.Equal(theirs.routes, base.routes) {
+		rl.setRoutes(path, c.Routes)
+		merged = append(merged, "routes")
+	}
+
+	if file == nil {
+		file = &diskState{}
+		rl.files = append(rl.files, file)
+	}
+	return merged, file.set(path, info, c)
+}
+
+// setRoutes replaces the routes coming from the config file at path, keeping the routes of the files
+// of higher precedence first.
+func (rl *ResponsesList) setRoutes(path string, routes []*Route) {
+	var kept []*Route
+	for _, route := range rl.Routes {
+		if rl.target(route.origin) != path {
+			kept = append(kept, route)
+		}
+	}
+	for _, route := range routes {
+		route.origin = path
+	}
+
+	rl.Routes = append(kept, routes...)
+	sort.SliceStable(rl.Routes, func(i, j int) bool {
+		return rl.rank(rl.target(rl.Routes[i].origin)) > rl.rank(rl.target(rl.Routes[j].origin))
+	})
+}
+
+// theirChanges returns the sorted keys which changed from base to theirs, but not from base to mine.
+func theirChanges(base, mine, theirs map[string][]byte) []string {
+	same := func(a, b map[string][]byte, key string) bool {
+		x, okx := a[key]
+		y, oky := b[key]
+		return okx == oky && bytes.Equal(x, y)
+	}
+
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string][]byte{base, theirs} {
+		for key := range m {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if same(base, mine, key) && !same(base, theirs, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// writeFileAtomic writes data into a temporary file next to path, then renames it into place.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// nothing's left to remove once renamed
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
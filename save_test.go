@@ -0,0 +1,166 @@
+package httplab
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveShrinkingConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".httplab")
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	rl.Add("long", &Response{Status: 200, Body: Body{Mode: BodyInput, Input: make([]byte, 1024)}})
+	rl.Add("short", &Response{Status: 204})
+	require.NoError(t, rl.Save(path))
+
+	rl.Del("long")
+	require.NoError(t, rl.Save(path))
+
+	// stale bytes would make it unparseable
+	loaded := NewResponsesList()
+	require.NoError(t, loaded.Load(path))
+	assert.Equal(t, []string{"short"}, loaded.Keys())
+
+	bak := NewResponsesList()
+	require.NoError(t, bak.Load(path+".bak"))
+	assert.Equal(t, []string{"long", "short"}, bak.Keys())
+
+	// no temporary file is left behind
+	files, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestSaveKeepsSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "httplab.json")
+	path := filepath.Join(dir, ".httplab")
+	require.NoError(t, os.WriteFile(target, []byte(`{"Responses": {}}`), 0600))
+	require.NoError(t, os.Symlink(target, path))
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path))
+	require.NoError(t, rl.Add("ok", &Response{Status: 200}).Save(path))
+
+	info, err := os.Lstat(path)
+	require.NoError(t, err)
+	assert.True(t, info.Mode()&os.ModeSymlink != 0)
+
+	info, err = os.Stat(target)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestSaveConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".httplab")
+	base := NewResponsesList()
+	require.NoError(t, base.Load(path))
+	base.Add("shared", &Response{Status: 200})
+	base.Add("theirs-deleted", &Response{Status: 200})
+	base.Add("both", &Response{Status: 200})
+	require.NoError(t, base.Save(path))
+
+	mine, theirs := NewResponsesList(), NewResponsesList()
+	require.NoError(t, mine.Load(path))
+	require.NoError(t, theirs.Load(path))
+
+	theirs.Add("theirs-added", &Response{Status: 201})
+	theirs.Del("theirs-deleted")
+	theirs.Add("both", &Response{Status: 500})
+	theirs.Routes = []*Route{{Path: "/theirs", Response: "theirs-added"}}
+	require.NoError(t, theirs.Save(path))
+
+	mine.Add("mine-added", &Response{Status: 202})
+	mine.Add("both", &Response{Status: 404})
+	err := mine.Save(path)
+	require.IsType(t, &ConflictError{}, err)
+
+	merged, err := mine.Merge(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"theirs-added", "theirs-deleted", "routes"}, merged)
+	require.NoError(t, mine.Save(path))
+
+	loaded := NewResponsesList()
+	require.NoError(t, loaded.Load(path))
+	assert.Equal(t, []string{"both", "mine-added", "shared", "theirs-added"}, loaded.Keys())
+	assert.Equal(t, 404, loaded.Get("both").Status)
+	require.Len(t, loaded.Routes, 1)
+	assert.Equal(t, "/theirs", loaded.Routes[0].Path)
+
+	// overwriting discards their changes
+	require.NoError(t, theirs.Load(path))
+	theirs.Del("shared")
+	require.NoError(t, theirs.Save(path))
+	mine.Add("again", &Response{Status: 200})
+	require.IsType(t, &ConflictError{}, mine.Save(path))
+	require.NoError(t, mine.Overwrite(path))
+	require.NoError(t, loaded.Load(path))
+	assert.NotNil(t, loaded.Get("shared"))
+
+	// a list which never loaded it can't clobber it either
+	err = NewResponsesList().Add("other", &Response{Status: 200}).Save(path)
+	assert.IsType(t, &ConflictError{}, err)
+}
+
+func TestSaveLayers(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigs(t, dir, map[string]string{
+		"system":    `{"Responses": {"a": {"Status": 200}, "b": {"Status": 200}}}`,
+		"user":      `{"Include": ["lib.json"], "Responses": {"b": {"Status": 201}}}`,
+		"lib.json":  `{"Responses": {"c": {"Status": 200}}}`,
+		".httplab":  `{"Responses": {}}`,
+		"untouched": `{"Responses": {"d": {"Status": 200}}}`,
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	rl := NewResponsesList()
+	require.NoError(t, rl.Load(path("system"), path("untouched"), path("user"), path(".httplab")))
+	rl.Get("a").Status = 500
+	rl.Get("c").Status = 501
+	rl.Add("b", &Response{Status: 502})
+	rl.Add("new", &Response{Status: 503})
+	require.NoError(t, rl.Save(path(".httplab")))
+	assert.Equal(t, path(".httplab"), rl.Origin("new"))
+
+	load := func(name string) *ResponsesList {
+		rl := NewResponsesList()
+		require.NoError(t, rl.Load(path(name)))
+		return rl
+	}
+	// the response overlaid by the user file is kept
+	assert.Equal(t, 500, load("system").Get("a").Status)
+	assert.Equal(t, 200, load("system").Get("b").Status)
+	assert.Equal(t, 502, load("user").Get("b").Status)
+	assert.Equal(t, 501, load("lib.json").Get("c").Status)
+	assert.Equal(t, []string{"new"}, load(".httplab").Keys())
+
+	// only the files which changed were written
+	_, err := os.Stat(path("untouched.bak"))
+	assert.True(t, os.IsNotExist(err))
+	data, err := os.ReadFile(path("user"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Include": [`)
+
+	// a conflict on any file is reported
+	other := NewResponsesList()
+	require.NoError(t, other.Load(path("lib.json")))
+	other.Add("d", &Response{Status: 200})
+	require.NoError(t, other.Save(path("lib.json")))
+
+	rl.Get("c").Status = 504
+	err = rl.Save(path(".httplab"))
+	require.IsType(t, &ConflictError{}, err)
+	assert.Equal(t, path("lib.json"), err.(*ConflictError).Path)
+
+	merged, err := rl.SaveMerging(path(".httplab"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d"}, merged)
+	assert.Equal(t, path("lib.json"), rl.Origin("d"))
+	assert.Equal(t, []string{"c", "d"}, load("lib.json").Keys())
+	assert.Equal(t, 504, load("lib.json").Get("c").Status)
+}
@@ -0,0 +1,133 @@
+package httplab
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+)
+
+// SequenceMode defines how a Sequence advances once all of its responses have been served.
+type SequenceMode string
+
+const (
+	// SequenceCycle starts over from the first response.
+	SequenceCycle SequenceMode = "cycle"
+	// SequenceStopAtLast keeps serving the last response.
+	SequenceStopAtLast SequenceMode = "stop-at-last"
+	// SequenceRandom picks a random response on each request, according to Weights.
+	SequenceRandom SequenceMode = "random"
+)
+
+// Sequence is a scripted series of responses, served one after the other as requests arrive.
+type Sequence struct {
+	// Responses are the names of the responses to be served.
+	Responses []string
+	// Mode defaults to SequenceCycle.
+	Mode SequenceMode `json:",omitempty"`
+	// Weights are the relative chances of each response when Mode is SequenceRandom.
+	// All responses are equally likely when empty.
+	Weights []int `json:",omitempty"`
+
+	counter uint64
+}
+
+func (s *Sequence) validate() error {
+	if len(s.Responses) == 0 {
+		return errors.New("no responses defined")
+	}
+
+	switch s.Mode {
+	case "", SequenceCycle, SequenceStopAtLast:
+	case SequenceRandom:
+		if len(s.Weights) == 0 {
+			break
+		}
+
+		if len(s.Weights) != len(s.Responses) {
+			return errors.New("weights and responses should have the same length")
+		}
+
+		var total int
+		for _, w := range s.Weights {
+			if w < 0 {
+				return errors.New("weights can't be negative")
+			}
+			total += w
+		}
+		if total == 0 {
+			return errors.New("at least one weight should be positive")
+		}
+	default:
+		return fmt.Errorf("unknown mode '%s'", s.Mode)
+	}
+
+	return nil
+}
+
+// Next advances the sequence and returns the name of the response to be served.
+// It is safe to be called concurrently.
+func (s *Sequence) Next() string {
+	n := atomic.AddUint64(&s.counter, 1) - 1
+
+	switch s.Mode {
+	case SequenceStopAtLast:
+		if n >= uint64(len(s.Responses)) {
+			n = uint64(len(s.Responses) - 1)
+		}
+		return s.Responses[n]
+	case SequenceRandom:
+		return s.Responses[s.pick()]
+	}
+	return s.Responses[n%uint64(len(s.Responses))]
+}
+
+func (s *Sequence) pick() int {
+	if len(s.Weights) == 0 {
+		return rand.Intn(len(s.Responses))
+	}
+
+	var total int
+	for _, w := range s.Weights {
+		total += w
+	}
+
+	n := rand.Intn(total)
+	for i, w := range s.Weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return len(s.Weights) - 1
+}
+
+// Position returns how many requests have been served by the sequence since the last reset.
+func (s *Sequence) Position() uint64 {
+	return atomic.LoadUint64(&s.counter)
+}
+
+// Reset starts the sequence over.
+func (s *Sequence) Reset() {
+	atomic.StoreUint64(&s.counter, 0)
+}
+
+// String describes the position of the last served response within the sequence.
+func (s *Sequence) String() string {
+	pos := s.Position()
+	n := uint64(len(s.Responses))
+
+	switch s.Mode {
+	case SequenceRandom:
+		return fmt.Sprintf("random, %d served", pos)
+	case SequenceStopAtLast:
+		if pos > n {
+			pos = n
+		}
+	default:
+		if pos > 0 {
+			pos = (pos-1)%n + 1
+		}
+	}
+	return fmt.Sprintf("%d/%d", pos, n)
+}
@@ -0,0 +1,108 @@
+package httplab
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceModes(t *testing.T) {
+	t.Run("cycle", func(t *testing.T) {
+		seq := &Sequence{Responses: []string{"a", "b", "c"}}
+		var got []string
+		for i := 0; i < 5; i++ {
+			got = append(got, seq.Next())
+		}
+		assert.Equal(t, []string{"a", "b", "c", "a", "b"}, got)
+		assert.Equal(t, "2/3", seq.String())
+
+		seq.Reset()
+		assert.Equal(t, "0/3", seq.String())
+		assert.Equal(t, "a", seq.Next())
+	})
+
+	t.Run("stop-at-last", func(t *testing.T) {
+		seq := &Sequence{Responses: []string{"503", "503", "200"}, Mode: SequenceStopAtLast}
+		var got []string
+		for i := 0; i < 5; i++ {
+			got = append(got, seq.Next())
+		}
+		assert.Equal(t, []string{"503", "503", "200", "200", "200"}, got)
+		assert.Equal(t, "3/3", seq.String())
+	})
+
+	t.Run("random", func(t *testing.T) {
+		seq := &Sequence{Responses: []string{"a", "b", "c"}, Mode: SequenceRandom, Weights: []int{0, 1, 0}}
+		require.NoError(t, seq.validate())
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, "b", seq.Next())
+		}
+	})
+}
+
+func TestSequenceValidate(t *testing.T) {
+	for _, seq := range []*Sequence{
+		{},
+		{Responses: []string{"a"}, Mode: "foo"},
+		{Responses: []string{"a"}, Mode: SequenceRandom, Weights: []int{1, 2}},
+		{Responses: []string{"a"}, Mode: SequenceRandom, Weights: []int{0}},
+		{Responses: []string{"a", "b"}, Mode: SequenceRandom, Weights: []int{-1, 2}},
+	} {
+		assert.Error(t, seq.validate(), "%+v should be invalid", seq)
+	}
+}
+
+func TestSequenceIsConcurrencySafe(t *testing.T) {
+	seq := &Sequence{Responses: []string{"a", "b"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seq.Next()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(100), seq.Position())
+}
+
+func TestResponsesListMatchSequence(t *testing.T) {
+	path := t.TempDir() + "/httplab.json"
+
+	rl := NewResponsesList()
+	rl.Add("unavailable", &Response{Status: 503}).Add("ok", &Response{Status: 200})
+	rl.Sequences["retry"] = &Sequence{
+		Responses: []string{"unavailable", "unavailable", "ok"},
+		Mode:      SequenceStopAtLast,
+	}
+	rl.Routes = []*Route{{Path: "/retry", Sequence: "retry"}}
+	require.NoError(t, rl.Save(path))
+
+	rl = NewResponsesList()
+	require.NoError(t, rl.Load(path))
+
+	req, _ := http.NewRequest("GET", "/retry", nil)
+	var statuses []int
+	for i := 0; i < 4; i++ {
+		_, resp := rl.Match(req)
+		require.NotNil(t, resp)
+		statuses = append(statuses, resp.Status)
+	}
+	assert.Equal(t, []int{503, 503, 200, 200}, statuses)
+
+	t.Run("Load() keeps positions", func(t *testing.T) {
+		require.NoError(t, rl.Load(path))
+		assert.Equal(t, uint64(4), rl.Sequences["retry"].Position())
+	})
+
+	t.Run("ResetSequences()", func(t *testing.T) {
+		rl.ResetSequences()
+		_, resp := rl.Match(req)
+		assert.Equal(t, 503, resp.Status)
+	})
+}
@@ -0,0 +1,305 @@
+package httplab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clientEventsBuffer is the amount of events queued per client, Broadcast drops the events of clients lagging further behind.
+const clientEventsBuffer = 64
+
+// Event is a Server-Sent Event.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	// Delay is waited before sending the event, when it's scripted on the config.
+	Delay time.Duration
+}
+
+// MarshalJSON writes Delay as a duration string and leaves empty fields out.
+func (e Event) MarshalJSON() ([]byte, error) {
+	var delay *jsonDuration
+	if e.Delay != 0 {
+		delay = durationPtr(e.Delay)
+	}
+	return json.Marshal(struct {
+		ID    string        `json:",omitempty"`
+		Event string        `json:",omitempty"`
+		Data  string        `json:",omitempty"`
+		Delay *jsonDuration `json:",omitempty"`
+	}{e.ID, e.Event, e.Data, delay})
+}
+
+// UnmarshalJSON reads Delay as a duration like "1s" or a number of milliseconds.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type alias Event
+	v := struct {
+		*alias
+		Delay jsonDuration
+	}{alias: (*alias)(e)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	e.Delay = time.Duration(v.Delay)
+	return nil
+}
+
+// WriteTo writes the event in the text/event-stream format.
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	if e.ID != "" {
+		fmt.Fprintf(buf, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(buf, "event: %s\n", e.Event)
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+	return buf.WriteTo(w)
+}
+
+// ParseEvent parses an event written as "field: value" lines, like:
+//
+//	id: 42
+//	event: update
+//	data: {"progress": 50}
+//
+// Lines without an id, event or data field are taken as data lines.
+func ParseEvent(s string) (Event, error) {
+	var e Event
+	var data []string
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "id":
+			e.ID = value
+		case "event":
+			e.Event = value
+		case "data":
+			data = append(data, value)
+		default:
+			data = append(data, line)
+		}
+	}
+
+	if e.ID == "" && e.Event == "" && len(data) == 1 && data[0] == "" {
+		return e, fmt.Errorf("SSE: the event is empty")
+	}
+	e.Data = strings.Join(data, "\n")
+	return e, nil
+}
+
+// EventClient is a client connected to an SSE response.
+type EventClient struct {
+	ID          int
+	RemoteAddr  string
+	Path        string
+	ConnectedAt time.Time
+	// Sent is the amount of events sent to the client.
+	Sent int
+}
+
+// String describes the client in a single line.
+func (c EventClient) String() string {
+	return fmt.Sprintf("#%d %s %s, %d events in %s", c.ID, c.RemoteAddr, c.Path, c.Sent,
+		time.Since(c.ConnectedAt).Truncate(time.Second))
+}
+
+type eventClient struct {
+	EventClient
+	events chan Event
+	done   chan struct{}
+}
+
+// EventBroker keeps track of the clients connected to SSE responses, and pushes events to them.
+type EventBroker struct {
+	// Notify, when set, is called each time a client connects or disconnects.
+	Notify func(c EventClient, connected bool)
+
+	mu      sync.Mutex
+	clients []*eventClient
+	lastID  int
+	closed  chan struct{}
+}
+
+// Close ends the streams of the connected clients, and of the ones connecting afterwards.
+// Servers should call it on shutdown, as http.Server.Shutdown waits for them otherwise.
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed == nil {
+		b.closed = make(chan struct{})
+	}
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+}
+
+// closing returns the channel closed by Close.
+func (b *EventBroker) closing() chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed == nil {
+		b.closed = make(chan struct{})
+	}
+	return b.closed
+}
+
+// Clients returns the connected clients, in the order they connected.
+func (b *EventBroker) Clients() []EventClient {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clients := make([]EventClient, len(b.clients))
+	for i, c := range b.clients {
+		clients[i] = c.EventClient
+	}
+	return clients
+}
+
+// Broadcast sends e to every connected client, it returns the amount of clients it was sent to.
+// It never blocks: the clients whose queue is full, since they don't keep up, miss e.
+func (b *EventBroker) Broadcast(e Event) int {
+	b.mu.Lock()
+	clients := append([]*eventClient(nil), b.clients...)
+	b.mu.Unlock()
+
+	n := 0
+	for _, c := range clients {
+		select {
+		case <-c.done:
+		case c.events <- e:
+			n++
+		default:
+		}
+	}
+	return n
+}
+
+// Serve keeps the connection open, sending the events scripted on the body of r one after the other
+// and the ones broadcast, until the client disconnects or b is closed.
+func (b *EventBroker) Serve(w http.ResponseWriter, req *http.Request, r *Response) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return fmt.Errorf("SSE: streaming is not supported")
+	}
+
+	hdr := w.Header()
+	if hdr.Get("Content-Type") == "" {
+		hdr.Set("Content-Type", "text/event-stream")
+	}
+	hdr.Set("Cache-Control", "no-cache")
+	hdr.Del("Content-Length")
+	w.WriteHeader(r.Status)
+	flusher.Flush()
+
+	c := b.connect(req)
+	defer b.disconnect(c)
+	closed := b.closing()
+
+	send := func(e Event) error {
+		if _, err := e.WriteTo(w); err != nil {
+			return err
+		}
+		flusher.Flush()
+
+		b.mu.Lock()
+		c.Sent++
+		b.mu.Unlock()
+		return nil
+	}
+
+	script := r.Body.Events
+	var next <-chan time.Time
+	if len(script) > 0 {
+		next = time.After(script[0].Delay)
+	}
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return nil
+		case <-closed:
+			return nil
+		case e := <-c.events:
+			if err := send(e); err != nil {
+				return err
+			}
+		case <-next:
+			if err := send(script[0]); err != nil {
+				return err
+			}
+			script = script[1:]
+			next = nil
+			if len(script) > 0 {
+				next = time.After(script[0].Delay)
+			}
+		}
+	}
+}
+
+func (b *EventBroker) connect(req *http.Request) *eventClient {
+	b.mu.Lock()
+	b.lastID++
+	c := &eventClient{
+		EventClient: EventClient{
+			ID:          b.lastID,
+			RemoteAddr:  req.RemoteAddr,
+			Path:        req.URL.Path,
+			ConnectedAt: time.Now(),
+		},
+		events: make(chan Event, clientEventsBuffer),
+		done:   make(chan struct{}),
+	}
+	b.clients = append(b.clients, c)
+	b.mu.Unlock()
+
+	if b.Notify != nil {
+		b.Notify(c.EventClient, true)
+	}
+	return c
+}
+
+func (b *EventBroker) disconnect(c *eventClient) {
+	close(c.done)
+
+	b.mu.Lock()
+	for i := range b.clients {
+		if b.clients[i] == c {
+			b.clients = append(b.clients[:i], b.clients[i+1:]...)
+			break
+		}
+	}
+	client := c.EventClient
+	b.mu.Unlock()
+
+	if b.Notify != nil {
+		b.Notify(client, false)
+	}
+}
+
+// eventsPayload writes events one after the other, as a plain body would send them.
+func eventsPayload(events []Event) []byte {
+	buf := &bytes.Buffer{}
+	for _, e := range events {
+		e.WriteTo(buf)
+	}
+	return buf.Bytes()
+}
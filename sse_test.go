@@ -0,0 +1,169 @@
+package httplab
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEvent(t *testing.T) {
+	e, err := ParseEvent("id: 42\nevent: update\ndata: {\"a\": 1}\nsecond line\n")
+	require.NoError(t, err)
+	assert.Equal(t, Event{ID: "42", Event: "update", Data: "{\"a\": 1}\nsecond line"}, e)
+
+	e, err = ParseEvent("just some data")
+	require.NoError(t, err)
+	assert.Equal(t, Event{Data: "just some data"}, e)
+
+	_, err = ParseEvent("\n")
+	assert.Error(t, err)
+}
+
+func TestEventWriteTo(t *testing.T) {
+	buf := &strings.Builder{}
+	_, err := Event{ID: "1", Event: "tick", Data: "a\nb"}.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "id: 1\nevent: tick\ndata: a\ndata: b\n\n", buf.String())
+}
+
+func TestEventsJSON(t *testing.T) {
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"Status": 200,
+		"Body": "data: typed",
+		"Events": [{"Event": "tick", "Data": "1", "Delay": "1s"}, {"Data": "2", "Delay": 500}]
+	}`), &resp))
+
+	assert.Equal(t, BodySSE, resp.Body.Mode)
+	assert.Equal(t, "data: typed", string(resp.Body.Input))
+	assert.Equal(t, []Event{
+		{Event: "tick", Data: "1", Delay: time.Second},
+		{Data: "2", Delay: 500 * time.Millisecond},
+	}, resp.Body.Events)
+
+	data, err := json.Marshal(&resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `{"Event":"tick","Data":"1","Delay":"1s"}`)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Status": 200, "Events": []}`), &resp))
+	assert.Equal(t, BodySSE, resp.Body.Mode)
+	data, err = json.Marshal(&resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Events":[]`)
+}
+
+func TestEventBroker(t *testing.T) {
+	b := &EventBroker{}
+	notifications := make(chan bool, 2)
+	b.Notify = func(c EventClient, connected bool) {
+		notifications <- connected
+	}
+
+	resp := &Response{
+		Status: 200,
+		Body: Body{Mode: BodySSE, Events: []Event{
+			{ID: "1", Data: "scripted", Delay: 10 * time.Millisecond},
+		}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b.Serve(w, req, resp)
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/events")
+	require.NoError(t, err)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	assert.True(t, <-notifications)
+
+	clients := b.Clients()
+	require.Len(t, clients, 1)
+	assert.Equal(t, "/events", clients[0].Path)
+
+	r := bufio.NewReader(res.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	assert.Equal(t, "id: 1\ndata: scripted\n", readEvent())
+	assert.Equal(t, 1, b.Broadcast(Event{Event: "typed", Data: "hello"}))
+	assert.Equal(t, "event: typed\ndata: hello\n", readEvent())
+
+	res.Body.Close()
+	select {
+	case connected := <-notifications:
+		assert.False(t, connected)
+	case <-time.After(time.Second):
+		t.Fatal("client disconnection wasn't detected")
+	}
+	assert.Empty(t, b.Clients())
+	assert.Equal(t, 0, b.Broadcast(Event{Data: "nobody"}))
+}
+
+func TestEventBrokerClose(t *testing.T) {
+	connected := make(chan bool, 2)
+	b := &EventBroker{Notify: func(c EventClient, ok bool) { connected <- ok }}
+	resp := &Response{Status: 200, Body: Body{Mode: BodySSE}}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		resp.Serve(w, req)
+	}))
+	brokers := &Brokers{Events: b, Sockets: &SocketBroker{}}
+	srv.Config.BaseContext = brokers.BaseContext
+	srv.Config.RegisterOnShutdown(brokers.Close)
+	srv.Start()
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.True(t, <-connected)
+	assert.Equal(t, 1, b.Broadcast(Event{Data: "served by the server's broker"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, srv.Config.Shutdown(ctx))
+	assert.False(t, <-connected)
+	assert.Empty(t, b.Clients())
+
+	// the stream ends, and the ones opened afterwards too
+	_, err = io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, b.Serve(rec, httptest.NewRequest("GET", "/events", nil), resp))
+	assert.Equal(t, 200, rec.Code)
+}
+
+func TestEventBrokerSlowClient(t *testing.T) {
+	b := &EventBroker{}
+	c := b.connect(httptest.NewRequest("GET", "/events", nil))
+	defer b.disconnect(c)
+
+	// the client never reads its events, once its queue is full they're dropped
+	for i := 0; i < clientEventsBuffer; i++ {
+		require.Equal(t, 1, b.Broadcast(Event{Data: "queued"}))
+	}
+	done := make(chan int)
+	go func() { done <- b.Broadcast(Event{Data: "dropped"}) }()
+	select {
+	case n := <-done:
+		assert.Equal(t, 0, n)
+	case <-time.After(time.Second):
+		t.Fatal("Broadcast blocked on a slow client")
+	}
+}
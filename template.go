@@ -0,0 +1,137 @@
+package httplab
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateContext is the data available to the templates of a BodyTemplate response.
+type TemplateContext struct {
+	Request *http.Request
+	// Query holds the parsed query string.
+	Query url.Values
+	// Body is the raw request body.
+	Body string
+	// JSON is the decoded request body, it's nil if the body isn't valid JSON.
+	JSON interface{}
+}
+
+// NewTemplateContext builds the template context for req.
+// The request body is read but remains available to subsequent readers.
+func NewTemplateContext(req *http.Request) (*TemplateContext, error) {
+	ctx := &TemplateContext{
+		Request: req,
+		Query:   req.URL.Query(),
+	}
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx.Body = string(body)
+		if err := json.Unmarshal(body, &ctx.JSON); err != nil {
+			ctx.JSON = nil
+		}
+	}
+
+	return ctx, nil
+}
+
+// Header returns the first value of the given request header.
+func (ctx *TemplateContext) Header(key string) string {
+	return ctx.Request.Header.Get(key)
+}
+
+// Segment returns the i-th segment of the request path, starting at 0.
+// e.g. Segment 1 of /users/42 is "42".
+func (ctx *TemplateContext) Segment(i int) string {
+	segments := strings.Split(strings.Trim(ctx.Request.URL.Path, "/"), "/")
+	if i < 0 || i >= len(segments) {
+		return ""
+	}
+	return segments[i]
+}
+
+var templateFuncs = template.FuncMap{
+	"now":  time.Now,
+	"uuid": newUUID,
+	"json": func(v interface{}) (string, error) {
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	},
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	// version 4, variant 10
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func renderTemplate(name, text string, ctx *TemplateContext) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, ctx); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Render evaluates the body and header templates of a BodyTemplate response against req,
+// and returns the resulting response. Other responses are returned as is.
+func (r *Response) Render(req *http.Request) (*Response, error) {
+	if r.Body.Mode != BodyTemplate {
+		return r, nil
+	}
+
+	ctx, err := NewTemplateContext(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := *r
+	rendered.Headers = http.Header{}
+	for key, values := range r.Headers {
+		for _, value := range values {
+			out, err := renderTemplate(key, value, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("Header %s: %v", key, err)
+			}
+			rendered.Headers.Add(key, string(out))
+		}
+	}
+
+	body, err := renderTemplate("body", string(r.Body.Input), ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Body: %v", err)
+	}
+	rendered.Body = Body{Mode: BodyInput, Input: body}
+
+	return &rendered, nil
+}
@@ -0,0 +1,88 @@
+package httplab
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseRender(t *testing.T) {
+	resp := &Response{
+		Status: 200,
+		Headers: http.Header{
+			"X-Request-Id": []string{`{{.Header "X-Request-Id"}}`},
+			"X-Static":     []string{"static"},
+		},
+		Body: Body{
+			Mode:  BodyTemplate,
+			Input: []byte(`{"id": "{{.Segment 1}}", "name": "{{.JSON.name}}", "q": "{{.Query.Get "q"}}", "uuid": "{{uuid}}"}`),
+		},
+	}
+
+	req, _ := http.NewRequest("POST", "/users/42?q=foo", bytes.NewBufferString(`{"name": "bob"}`))
+	req.Header.Set("X-Request-Id", "abc")
+
+	rendered, err := resp.Render(req)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, rendered.Write(rec))
+
+	assert.Equal(t, "abc", rec.Header().Get("X-Request-Id"))
+	assert.Equal(t, "static", rec.Header().Get("X-Static"))
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "42", body["id"])
+	assert.Equal(t, "bob", body["name"])
+	assert.Equal(t, "foo", body["q"])
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), body["uuid"])
+
+	t.Run("Request body can still be read", func(t *testing.T) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"name": "bob"}`, string(body))
+	})
+
+	t.Run("Original response is untouched", func(t *testing.T) {
+		assert.Equal(t, BodyTemplate, resp.Body.Mode)
+		assert.Equal(t, `{{.Header "X-Request-Id"}}`, resp.Headers.Get("X-Request-Id"))
+	})
+}
+
+func TestResponseRenderErrors(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	for _, tmpl := range []string{"{{.Foo", "{{.Unknown}}", "{{nofunc}}"} {
+		resp := &Response{Body: Body{Mode: BodyTemplate, Input: []byte(tmpl)}}
+		_, err := resp.Render(req)
+		assert.Error(t, err, "template '%s' should fail", tmpl)
+	}
+}
+
+func TestResponseRenderIgnoresNonTemplates(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	resp := &Response{Body: Body{Mode: BodyInput, Input: []byte("{{.Foo}}")}}
+
+	rendered, err := resp.Render(req)
+	require.NoError(t, err)
+	assert.Equal(t, resp, rendered)
+}
+
+func TestTemplateResponseJSON(t *testing.T) {
+	resp := &Response{Status: 200, Body: Body{Mode: BodyTemplate, Input: []byte("{{now}}")}}
+	buf, err := json.Marshal(resp)
+	require.NoError(t, err)
+
+	var r Response
+	require.NoError(t, json.Unmarshal(buf, &r))
+	assert.Equal(t, BodyTemplate, r.Body.Mode)
+	assert.Equal(t, "{{now}}", string(r.Body.Input))
+}
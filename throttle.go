@@ -0,0 +1,141 @@
+package httplab
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultChunkSize is the chunk size used when Throttle.ChunkSize isn't set.
+const defaultChunkSize = 4096
+
+// Throttle streams a response body in chunks, slowly.
+type Throttle struct {
+	// BytesPerSecond limits the throughput, 0 means no limit.
+	BytesPerSecond int64
+	// ChunkSize is the amount of bytes flushed at once, 4KB by default.
+	// When BytesPerSecond is set it defaults to a tenth of it, so the body trickles.
+	ChunkSize int
+	// Pause is waited between chunks.
+	Pause time.Duration
+	// Chunked sends the body with "Transfer-Encoding: chunked" instead of a Content-Length.
+	Chunked bool
+}
+
+// MarshalJSON writes Pause as a duration string.
+func (t Throttle) MarshalJSON() ([]byte, error) {
+	type alias Throttle
+	return json.Marshal(struct {
+		alias
+		Pause jsonDuration `json:",omitempty"`
+	}{alias(t), jsonDuration(t.Pause)})
+}
+
+// UnmarshalJSON reads Pause as a duration like "100ms" or a number of milliseconds.
+func (t *Throttle) UnmarshalJSON(data []byte) error {
+	type alias Throttle
+	v := struct {
+		*alias
+		Pause jsonDuration
+	}{alias: (*alias)(t)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	t.Pause = time.Duration(v.Pause)
+	return nil
+}
+
+func (t *Throttle) chunkSize() int {
+	switch {
+	case t.ChunkSize > 0:
+		return t.ChunkSize
+	case t.BytesPerSecond >= 10:
+		return int(t.BytesPerSecond / 10)
+	case t.BytesPerSecond > 0:
+		return 1
+	}
+	return defaultChunkSize
+}
+
+// copy writes src into w chunk by chunk, flushing each of them and keeping to the throughput limit.
+func (t *Throttle) copy(w http.ResponseWriter, src io.Reader) error {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, t.chunkSize())
+
+	start := time.Now()
+	var sent int64
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			if sent > 0 {
+				time.Sleep(t.Pause)
+			}
+			if t.BytesPerSecond > 0 {
+				// wait until the previous chunks are due
+				due := start.Add(time.Duration(sent * int64(time.Second) / t.BytesPerSecond))
+				time.Sleep(time.Until(due))
+			}
+
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			sent += int64(n)
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Reader opens the body content along with its size. File bodies are reopened, so concurrent readers don't interfere.
+func (body *Body) Reader() (io.ReadCloser, int64, error) {
+	if body.Mode == BodyFile {
+		if body.File == nil {
+			return io.NopCloser(bytes.NewReader(nil)), 0, nil
+		}
+
+		f, err := os.Open(body.File.Name())
+		if err != nil {
+			return nil, 0, err
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, stat.Size(), nil
+	}
+
+	payload := body.Payload()
+	return io.NopCloser(bytes.NewReader(payload)), int64(len(payload)), nil
+}
+
+// writeThrottled streams the body of r according to its Throttle, headers have to be already set.
+func (r *Response) writeThrottled(w http.ResponseWriter) error {
+	body, size, err := r.Body.Reader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	defer body.Close()
+
+	w.Header().Del("Content-Length")
+	// trailers need the body to be chunked over HTTP/1.1
+	if !r.Throttle.Chunked && len(r.Trailers) == 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(r.Status)
+
+	return r.Throttle.copy(w, body)
+}
@@ -0,0 +1,99 @@
+package httplab
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveThrottled(t *testing.T, resp *Response) (*http.Response, string, time.Duration) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		resp.Write(w)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(body), time.Since(start)
+}
+
+func TestThrottleChunked(t *testing.T) {
+	body := strings.Repeat("x", 200)
+	resp := &Response{
+		Status:   200,
+		Body:     Body{Mode: BodyInput, Input: []byte(body)},
+		Throttle: &Throttle{BytesPerSecond: 1000, ChunkSize: 50, Chunked: true},
+	}
+
+	res, got, elapsed := serveThrottled(t, resp)
+	assert.Equal(t, body, got)
+	assert.Equal(t, []string{"chunked"}, res.TransferEncoding)
+	assert.Equal(t, int64(-1), res.ContentLength)
+	// chunks are sent at 0, 50, 100 and 150ms
+	assert.True(t, elapsed >= 150*time.Millisecond, "took %s", elapsed)
+}
+
+func TestThrottleContentLength(t *testing.T) {
+	resp := &Response{
+		Status:   201,
+		Headers:  http.Header{"Content-Length": {"1"}},
+		Body:     Body{Mode: BodyInput, Input: []byte("abcdef")},
+		Throttle: &Throttle{ChunkSize: 2, Pause: 30 * time.Millisecond},
+	}
+
+	res, got, elapsed := serveThrottled(t, resp)
+	assert.Equal(t, 201, res.StatusCode)
+	assert.Equal(t, "abcdef", got)
+	assert.Equal(t, int64(6), res.ContentLength)
+	assert.Empty(t, res.TransferEncoding)
+	assert.True(t, elapsed >= 60*time.Millisecond, "took %s", elapsed)
+}
+
+func TestThrottleBodyFile(t *testing.T) {
+	resp := &Response{Status: 200, Throttle: &Throttle{}}
+	require.NoError(t, resp.Body.SetFile("./testdata/index.html"))
+
+	res, got, _ := serveThrottled(t, resp)
+	assert.Equal(t, "<html></html>", strings.TrimSpace(got))
+	assert.Equal(t, int64(len(got)), res.ContentLength)
+}
+
+func TestThrottleChunkSize(t *testing.T) {
+	assert.Equal(t, defaultChunkSize, (&Throttle{}).chunkSize())
+	assert.Equal(t, 100, (&Throttle{BytesPerSecond: 1000}).chunkSize())
+	assert.Equal(t, 1, (&Throttle{BytesPerSecond: 5}).chunkSize())
+	assert.Equal(t, 10, (&Throttle{BytesPerSecond: 1000, ChunkSize: 10}).chunkSize())
+}
+
+func TestThrottleJSON(t *testing.T) {
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"Status": 200,
+		"Throttle": {"BytesPerSecond": 1024, "ChunkSize": 256, "Pause": "100ms", "Chunked": true}
+	}`), &resp))
+	assert.Equal(t, &Throttle{BytesPerSecond: 1024, ChunkSize: 256, Pause: 100 * time.Millisecond, Chunked: true}, resp.Throttle)
+
+	buf, err := json.Marshal(&resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf), `"Pause":"100ms"`)
+
+	var again Response
+	require.NoError(t, json.Unmarshal(buf, &again))
+	assert.Equal(t, resp.Throttle, again.Throttle)
+
+	buf, err = json.Marshal(&Response{Status: 200})
+	require.NoError(t, err)
+	assert.NotContains(t, string(buf), "Throttle")
+}
@@ -0,0 +1,196 @@
+package httplab
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CertificateHosts are the hosts a generated certificate is valid for.
+var CertificateHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// Certificate is a self-signed CA together with a leaf certificate issued by it.
+// All the fields are PEM encoded.
+type Certificate struct {
+	CA   []byte
+	Cert []byte
+	Key  []byte
+}
+
+// GenerateCertificate creates an in-memory CA and a leaf certificate valid for the given hosts.
+// When no hosts are given, CertificateHosts are used.
+func GenerateCertificate(hosts ...string) (*Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = CertificateHosts
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	caSerial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          caSerial,
+		Subject:               pkix.Name{Organization: []string{"HTTPLab"}, CommonName: "HTTPLab CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"HTTPLab"}, CommonName: hosts[0]},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Certificate{
+		CA:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// newSerialNumber returns a random 128 bits certificate serial number.
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// TLSCertificate returns the leaf certificate, chained to its CA, ready to be used by a tls.Config.
+func (c *Certificate) TLSCertificate() (tls.Certificate, error) {
+	chain := append(append([]byte{}, c.Cert...), c.CA...)
+	return tls.X509KeyPair(chain, c.Key)
+}
+
+// WriteFiles writes the CA, the certificate and its key as ca.pem, cert.pem and key.pem into dir.
+func (c *Certificate) WriteFiles(dir string) error {
+	dir = ExpandPath(dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{"ca.pem", c.CA, 0644},
+		{"cert.pem", c.Cert, 0644},
+		{"key.pem", c.Key, 0600},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), f.data, f.perm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewTLSConfig returns a TLS configuration serving the given cert and key files.
+// If both are empty, a self-signed certificate is generated and, when certDir is not empty, written into it.
+func NewTLSConfig(certFile, keyFile, certDir string) (*tls.Config, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("both cert and key have to be specified")
+	}
+
+	var (
+		cert tls.Certificate
+		err  error
+	)
+
+	if certFile != "" {
+		cert, err = tls.LoadX509KeyPair(ExpandPath(certFile), ExpandPath(keyFile))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		gen, err := GenerateCertificate()
+		if err != nil {
+			return nil, err
+		}
+
+		if certDir != "" {
+			if err := gen.WriteFiles(certDir); err != nil {
+				return nil, err
+			}
+		}
+
+		cert, err = gen.TLSCertificate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// TLSVersionName returns the human readable name of a TLS version.
+func TLSVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return "unknown"
+}
@@ -0,0 +1,121 @@
+package httplab
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCertificate(t *testing.T) {
+	cert, err := GenerateCertificate()
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(cert.CA))
+
+	tlsCert, err := cert.TLSCertificate()
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	require.NoError(t, err)
+
+	for _, host := range CertificateHosts {
+		_, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: pool})
+		assert.NoError(t, err, "certificate should be valid for %s", host)
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: pool})
+	assert.Error(t, err)
+
+	t.Run("WriteFiles()", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, cert.WriteFiles(dir))
+
+		for _, name := range []string{"ca.pem", "cert.pem", "key.pem"} {
+			_, err := os.Stat(filepath.Join(dir, name))
+			assert.NoError(t, err)
+		}
+
+		_, err := NewTLSConfig(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), "")
+		assert.NoError(t, err)
+	})
+}
+
+func TestNewTLSConfigRequiresCertAndKey(t *testing.T) {
+	_, err := NewTLSConfig("cert.pem", "", "")
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig("", "key.pem", "")
+	assert.Error(t, err)
+}
+
+func TestDumpRequestWithTLS(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.TLS = &tls.ConnectionState{
+		Version:     tls.VersionTLS13,
+		CipherSuite: tls.TLS_AES_128_GCM_SHA256,
+		ServerName:  "localhost",
+	}
+
+	buf, err := DumpRequest(req)
+	require.NoError(t, err)
+
+	lines := strings.Split(string(Decolorize(buf)), "\n")
+	assert.Equal(t, "TLS: TLS 1.3, TLS_AES_128_GCM_SHA256, SNI=localhost", lines[1])
+}
+
+func TestTLSListener(t *testing.T) {
+	config, err := NewTLSConfig("", "", "")
+	require.NoError(t, err)
+	config.NextProtos = []string{"h2", "http/1.1"}
+
+	type served struct {
+		tls bool
+		r   *Request
+	}
+	requests := make(chan served, 1)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r, err := NewRequest(req)
+		require.NoError(t, err)
+		requests <- served{req.TLS != nil, r}
+	}))
+	srv.Listener = NewListener(tls.NewListener(srv.Listener, config))
+	srv.Config.TLSConfig = config
+	srv.Config.ConnContext = ConnContext
+	srv.Start()
+	defer srv.Close()
+
+	for _, h2 := range []bool{true, false} {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: h2,
+		}}
+		req, err := http.NewRequest("GET", "https://"+srv.Listener.Addr().String()+"/tls", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Z", "1")
+		req.Header.Set("X-A", "2")
+
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+
+		s := <-requests
+		assert.True(t, s.tls, "h2: %v", h2)
+		require.NotNil(t, s.r.TLS, "h2: %v", h2)
+		if h2 {
+			assert.Equal(t, "HTTP/2.0", s.r.Proto)
+			assert.NotZero(t, s.r.StreamID)
+			assert.NotEmpty(t, s.r.PseudoHeaders)
+		} else {
+			assert.Equal(t, "HTTP/1.1", s.r.Proto)
+		}
+	}
+}
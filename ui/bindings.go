@@ -66,14 +66,21 @@ var Bindings = &bindings{
 	{0xFF, "Shift+Tab", "Previous Input", nil, nil}, // only to display on help
 	{gocui.KeyCtrlA, "Ctrl+a", "Update Response", nil, onUpdateResponse},
 	{gocui.KeyCtrlR, "Ctrl+r", "Reset Request history", nil, onResetRequests},
+	{gocui.KeyCtrlK, "Ctrl+k", "Reset Sequences", nil, onResetSequences},
 	{gocui.KeyCtrlS, "Ctrl+s", "Save Response as", nil, onSaveResponseAs},
 	{gocui.KeyCtrlF, "Ctrl+f", "Save Request as", nil, onSaveRequestAs},
+	{gocui.KeyCtrlX, "Ctrl+x", "Export Request history as", nil, onExportRequestsAs},
+	{gocui.KeyCtrlP, "Ctrl+p", "Replay Request to", nil, onReplayRequest},
+	{gocui.KeyCtrlE, "Ctrl+e", "Toggle Break on upstream responses", nil, onToggleBreak},
 	{gocui.KeyCtrlL, "Ctrl+l", "Toggle Responses list", nil, onToggleResponsesList},
 	{gocui.KeyCtrlT, "Ctrl+t", "Toggle Response builder", nil, onToggleResponseBuilder},
 	{gocui.KeyCtrlO, "Ctrl+o", "Open Body file...", nil, onOpenFile},
 	{gocui.KeyCtrlB, "Ctrl+b", "Switch Body mode", nil, onSwitchBodyMode},
+	{gocui.KeyCtrlN, "Ctrl+n", "Send SSE event", nil, onSendEvent},
+	{gocui.KeyCtrlU, "Ctrl+u", "Toggle SSE clients list", nil, onToggleEventClients},
+	{gocui.KeyCtrlG, "Ctrl+g", "Toggle WebSocket frames", nil, onToggleFrames},
 	{gocui.KeyCtrlW, "Ctrl+w", "Toggle line wrapping", nil, onTogglLineWrapping},
-	{'q', "q", "Close Popup", []string{"bindings", "responses"}, onClosePopup},
+	{'q', "q", "Close Popup", []string{"bindings", "responses", "sse-clients"}, onClosePopup},
 	{gocui.KeyPgup, "PgUp", "Previous Request", nil, onPrevRequest},
 	{gocui.KeyPgdn, "PgDown", "Next Request", nil, onNextRequest},
 	{gocui.KeyCtrlC, "Ctrl+c", "Quit", nil, onQuit},
@@ -87,16 +94,31 @@ func onNextView(ui *UI) ActionFn {
 
 func onUpdateResponse(ui *UI) ActionFn {
 	return func(g *gocui.Gui, v *gocui.View) error {
+		if ui.held != nil {
+			return ui.sendHeldResponse(g)
+		}
 		return ui.updateResponse(g)
 	}
 }
 
+func onToggleBreak(ui *UI) ActionFn {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		return ui.toggleBreak(g)
+	}
+}
+
 func onResetRequests(ui *UI) ActionFn {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		return ui.resetRequests(g)
 	}
 }
 
+func onResetSequences(ui *UI) ActionFn {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		return ui.resetSequences(g)
+	}
+}
+
 func onSaveResponseAs(ui *UI) ActionFn {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		return ui.saveResponsePopup(g)
@@ -109,10 +131,22 @@ func onSaveRequestAs(ui *UI) ActionFn {
 	}
 }
 
+func onExportRequestsAs(ui *UI) ActionFn {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		return ui.exportRequestsPopup(g)
+	}
+}
+
+func onReplayRequest(ui *UI) ActionFn {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		return ui.replayRequestPopup(g)
+	}
+}
+
 func onToggleResponsesList(ui *UI) ActionFn {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		if err := ui.toggleResponsesLoader(g); err != nil {
-			ui.Info(g, err.Error())
+			ui.Info(g, "%v", err)
 		}
 		return nil
 	}
@@ -121,7 +155,7 @@ func onToggleResponsesList(ui *UI) ActionFn {
 func onToggleResponseBuilder(ui *UI) ActionFn {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		if err := ui.toggleResponseBuilder(g); err != nil {
-			ui.Info(g, err.Error())
+			ui.Info(g, "%v", err)
 		}
 		return nil
 	}
@@ -129,7 +163,10 @@ func onToggleResponseBuilder(ui *UI) ActionFn {
 
 func onOpenFile(ui *UI) ActionFn {
 	return func(g *gocui.Gui, v *gocui.View) error {
-		return ui.openBodyFilePopup(g)
+		if err := ui.openBodyFilePopup(g); err != nil {
+			ui.Info(g, "%v", err)
+		}
+		return nil
 	}
 }
 
@@ -139,6 +176,33 @@ func onSwitchBodyMode(ui *UI) ActionFn {
 	}
 }
 
+func onSendEvent(ui *UI) ActionFn {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if err := ui.sendEvent(g); err != nil {
+			ui.Info(g, "%v", err)
+		}
+		return nil
+	}
+}
+
+func onToggleEventClients(ui *UI) ActionFn {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if err := ui.toggleEventClients(g); err != nil {
+			ui.Info(g, "%v", err)
+		}
+		return nil
+	}
+}
+
+func onToggleFrames(ui *UI) ActionFn {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if err := ui.toggleFrames(g); err != nil {
+			ui.Info(g, "%v", err)
+		}
+		return nil
+	}
+}
+
 func onTogglLineWrapping(ui *UI) ActionFn {
 	return func(g *gocui.Gui, v *gocui.View) error {
 		return ui.toggleLineWrap(g)
@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gchaincl/httplab"
+	"github.com/jroimartin/gocui"
+)
+
+// saveResponses saves the responses into the config file, responsesLock has to be held.
+// When the file was modified by someone else meanwhile, a popup offers to merge or to overwrite their changes.
+func (ui *UI) saveResponses(g *gocui.Gui) error {
+	err := ui.responses.Save(ui.configPath)
+	if conflict, ok := err.(*httplab.ConflictError); ok {
+		// the popup is opened once the current one, if any, is closed
+		g.Update(func(g *gocui.Gui) error {
+			return ui.openConflictPopup(g, conflict.Path)
+		})
+	}
+	return err
+}
+
+// openConflictPopup asks what to do with the changes made to the config file at path.
+func (ui *UI) openConflictPopup(g *gocui.Gui, path string) error {
+	if err := ui.closePopup(g, ui.currentPopup); err != nil {
+		return err
+	}
+
+	lines := []string{
+		path + " was modified since it was loaded,",
+		"by another HTTPLab for instance.",
+		"",
+		"  m  merge their changes and save",
+		"  o  overwrite their changes",
+		"  q  cancel, nothing is saved",
+	}
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+
+	popup, err := ui.openPopup(g, ConflictView, width+2, len(lines)+1)
+	if err != nil {
+		return err
+	}
+
+	onMerge := func(g *gocui.Gui, v *gocui.View) error {
+		ui.responsesLock.Lock()
+		merged, err := ui.responses.Merge(path)
+		if err == nil {
+			err = ui.saveResponses(g)
+		}
+		ui.responsesLock.Unlock()
+
+		switch {
+		case err != nil:
+			ui.Info(g, "%v", err)
+		case len(merged) == 0:
+			ui.Info(g, "Responses saved, there was nothing to merge")
+		default:
+			ui.Info(g, "Responses saved, merged %s", strings.Join(merged, ", "))
+		}
+		return ui.closePopup(g, ConflictView)
+	}
+
+	onOverwrite := func(g *gocui.Gui, v *gocui.View) error {
+		ui.responsesLock.Lock()
+		err := ui.responses.Overwrite(ui.configPath)
+		ui.responsesLock.Unlock()
+
+		if err != nil {
+			ui.Info(g, "%v", err)
+		} else {
+			ui.Info(g, "Responses saved over %s", path)
+		}
+		return ui.closePopup(g, ConflictView)
+	}
+
+	onQuit := func(g *gocui.Gui, v *gocui.View) error {
+		ui.Info(g, "Responses not saved")
+		return ui.closePopup(g, ConflictView)
+	}
+
+	view := []string{popup.Name()}
+	(&bindings{
+		{'m', "", "", view, func(*UI) ActionFn { return onMerge }},
+		{'o', "", "", view, func(*UI) ActionFn { return onOverwrite }},
+		{'q', "", "", view, func(*UI) ActionFn { return onQuit }},
+	}).Apply(ui, g)
+
+	popup.Title = "Config file changed"
+	for _, line := range lines {
+		fmt.Fprintln(popup, line)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"github.com/jroimartin/gocui"
 )
@@ -92,3 +93,35 @@ func (e *numberEditor) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Mod
 		}
 	}
 }
+
+// delayEditor accepts the characters of the delays understood by httplab.ParseDelay,
+// like "250ms", "uniform 100ms 300ms" or "p50=100ms p99=1.5s", on a single line.
+// Durations take the µ of "500µs", as Delay.String writes them.
+type delayEditor struct {
+	maxLength int
+}
+
+// isDelayRune tells whether ch can be part of a delay.
+func isDelayRune(ch rune) bool {
+	return ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch == '.' || ch == '=' || ch == 'µ' || ch == 'μ'
+}
+
+func (e *delayEditor) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	x, _ := v.Cursor()
+	length := utf8.RuneCountInString(v.Buffer())
+	switch {
+	case isDelayRune(ch), key == gocui.KeySpace:
+		if length > e.maxLength+1 {
+			return
+		}
+		gocui.DefaultEditor.Edit(v, key, ch, mod)
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		v.EditDelete(true)
+	case key == gocui.KeyArrowLeft:
+		v.MoveCursor(-1, 0, false)
+	case key == gocui.KeyArrowRight:
+		if x < length-1 {
+			v.MoveCursor(1, 0, false)
+		}
+	}
+}
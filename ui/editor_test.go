@@ -0,0 +1,22 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/gchaincl/httplab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelayRunes(t *testing.T) {
+	// the delays shown on the view can be typed back
+	for _, s := range []string{"500µs", "1.5s", "uniform 100ms 300ms", "normal 200ms 50ms", "p50=100ms p99=1.5s"} {
+		d, err := httplab.ParseDelay(s)
+		require.NoError(t, err, s)
+		for _, ch := range d.String() {
+			assert.True(t, ch == ' ' || isDelayRune(ch), "%q of %q", ch, d.String())
+		}
+	}
+
+	assert.False(t, isDelayRune(','))
+}
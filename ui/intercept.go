@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gchaincl/httplab"
+	"github.com/jroimartin/gocui"
+)
+
+// heldResponse is an upstream response waiting to be edited on the response builder.
+type heldResponse struct {
+	resp *httplab.Response
+	// prev is the builder response, restored once the held one is sent.
+	prev *httplab.Response
+	ch   chan *httplab.Response
+}
+
+// Breaking tells whether upstream responses are being held.
+func (ui *UI) Breaking() bool {
+	return atomic.LoadInt32(&ui.breaking) == 1
+}
+
+// Intercept holds resp on the response builder until it's sent with Ctrl+a, returning the edited response.
+// Responses are held one at a time, the others wait for their turn.
+func (ui *UI) Intercept(g *gocui.Gui, req *http.Request, resp *httplab.Response) (*httplab.Response, error) {
+	ui.holdLock.Lock()
+	defer ui.holdLock.Unlock()
+
+	if !ui.Breaking() {
+		return resp, nil
+	}
+
+	h := &heldResponse{resp: resp, ch: make(chan *httplab.Response, 1)}
+	g.Update(func(g *gocui.Gui) error {
+		return ui.hold(g, h, req)
+	})
+
+	select {
+	case resp := <-h.ch:
+		return resp, nil
+	case <-req.Context().Done():
+		g.Update(func(g *gocui.Gui) error {
+			if ui.held == h {
+				ui.release(g, h.resp)
+				ui.Info(g, "Client gone, held response dropped")
+			}
+			return nil
+		})
+		return nil, req.Context().Err()
+	}
+}
+
+func (ui *UI) hold(g *gocui.Gui, h *heldResponse, req *http.Request) error {
+	if ui.hideResponseBuilder {
+		ui.hideResponseBuilder = false
+		if err := ui.Layout(g); err != nil {
+			return err
+		}
+	}
+
+	h.prev = ui.resp
+	ui.held = h
+	ui.restoreResponse(g, h.resp)
+	ui.Info(g, "Holding response to %s %s, Ctrl+a sends it", req.Method, req.URL.Path)
+	return ui.setView(g, StatusView)
+}
+
+// release sends resp in place of the held response, and restores the builder.
+func (ui *UI) release(g *gocui.Gui, resp *httplab.Response) {
+	h := ui.held
+	if h == nil {
+		return
+	}
+
+	ui.held = nil
+	h.ch <- resp
+	ui.restoreResponse(g, h.prev)
+}
+
+// sendHeldResponse sends the held response as edited on the response builder.
+func (ui *UI) sendHeldResponse(g *gocui.Gui) error {
+	resp, err := ui.currentResponse(g)
+	if err != nil {
+		ui.Info(g, "%v", err)
+		return nil
+	}
+
+	ui.release(g, resp)
+	ui.Info(g, "Response sent!")
+	return nil
+}
+
+func (ui *UI) toggleBreak(g *gocui.Gui) error {
+	if ui.Upstream == "" {
+		ui.Info(g, "Break is only available with --upstream")
+		return nil
+	}
+
+	if ui.Breaking() {
+		atomic.StoreInt32(&ui.breaking, 0)
+		if ui.held != nil {
+			ui.release(g, ui.held.resp)
+		}
+		ui.Info(g, "Break disabled, responses are sent as they arrive")
+		return nil
+	}
+
+	atomic.StoreInt32(&ui.breaking, 1)
+	ui.Info(g, "Break enabled, responses are held to be edited")
+	return nil
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// configPollInterval is how often the config file is checked for changes.
+const configPollInterval = time.Second
+
+// watchConfig reloads the responses every interval when the config file changes on disk, reporting
+// what changed, until ctx is done. Parse errors are reported once, the last good config is kept meanwhile.
+func (ui *UI) watchConfig(ctx context.Context, g *gocui.Gui, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ui.responsesLock.Lock()
+		diff, err := ui.responses.Reload(ui.configPaths...)
+		ui.responsesLock.Unlock()
+
+		if err != nil {
+			if err.Error() != lastErr {
+				g.Update(func(g *gocui.Gui) error {
+					ui.Info(g, "Config not reloaded: %v", err)
+					return nil
+				})
+			}
+			lastErr = err.Error()
+			continue
+		}
+		lastErr = ""
+
+		if diff == nil {
+			continue
+		}
+
+		g.Update(func(g *gocui.Gui) error {
+			ui.Info(g, "Config reloaded: %s", diff)
+			if name := ui.resp.Name; name != "" && diff.HasChanged(name) {
+				return ui.offerReapply(g, name)
+			}
+			return nil
+		})
+	}
+}
+
+// offerReapply asks whether to apply the new version of the response name, the current response came from.
+func (ui *UI) offerReapply(g *gocui.Gui, name string) error {
+	// don't get in the way of another popup
+	if ui.currentPopup != "" {
+		ui.Info(g, "Response '%s' changed on disk, Ctrl+l loads it again", name)
+		return nil
+	}
+
+	lines := []string{
+		fmt.Sprintf("Response '%s' changed on disk.", name),
+		"",
+		"  a  apply the new version",
+		"  q  keep the current one",
+	}
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+
+	popup, err := ui.openPopup(g, ReloadView, width+2, len(lines)+1)
+	if err != nil {
+		return err
+	}
+
+	onApply := func(g *gocui.Gui, v *gocui.View) error {
+		if err := ui.closePopup(g, ReloadView); err != nil {
+			return err
+		}
+
+		ui.responsesLock.RLock()
+		resp := ui.responses.Get(name)
+		ui.responsesLock.RUnlock()
+		if resp == nil {
+			ui.Info(g, "Response '%s' was removed", name)
+			return nil
+		}
+		ui.restoreResponse(g, resp)
+		return nil
+	}
+
+	onQuit := func(g *gocui.Gui, v *gocui.View) error {
+		return ui.closePopup(g, ReloadView)
+	}
+
+	view := []string{popup.Name()}
+	(&bindings{
+		{'a', "", "", view, func(*UI) ActionFn { return onApply }},
+		{'q', "", "", view, func(*UI) ActionFn { return onQuit }},
+	}).Apply(ui, g)
+
+	popup.Title = "Config reloaded"
+	for _, line := range lines {
+		fmt.Fprintln(popup, line)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gchaincl/httplab"
+	"github.com/jroimartin/gocui"
+)
+
+// watchEventClients reports on the info bar the SSE clients connecting and disconnecting,
+// keeping the body title and the clients list up to date.
+func (ui *UI) watchEventClients(g *gocui.Gui) {
+	ui.Brokers.Events.Notify = func(c httplab.EventClient, connected bool) {
+		if connected {
+			ui.Info(g, "SSE client #%d connected from %s", c.ID, c.RemoteAddr)
+		} else {
+			ui.Info(g, "SSE client #%d disconnected, %d events sent", c.ID, c.Sent)
+		}
+
+		g.Update(func(g *gocui.Gui) error {
+			if v, err := g.View(BodyView); err == nil {
+				v.Title = ui.bodyTitle()
+			}
+			if ui.currentPopup == EventClientsView {
+				return ui.renderEventClients(g)
+			}
+			return nil
+		})
+	}
+}
+
+// sendEvent sends the event typed on the body to every SSE client.
+func (ui *UI) sendEvent(g *gocui.Gui) error {
+	if ui.resp.Body.Mode != httplab.BodySSE {
+		ui.Info(g, "Events are sent on SSE body mode, switch to it with Ctrl+b")
+		return nil
+	}
+
+	e, err := httplab.ParseEvent(getViewBuffer(g, BodyView))
+	if err != nil {
+		ui.Info(g, "%v", err)
+		return nil
+	}
+
+	n := ui.Brokers.Events.Broadcast(e)
+	ui.Info(g, "Event sent to %d SSE clients", n)
+	return nil
+}
+
+func (ui *UI) toggleEventClients(g *gocui.Gui) error {
+	if ui.currentPopup == EventClientsView {
+		return ui.closePopup(g, EventClientsView)
+	}
+
+	clients := ui.Brokers.Events.Clients()
+	if len(clients) == 0 {
+		return errors.New("No SSE clients connected")
+	}
+
+	maxX, _ := g.Size()
+	popup, err := ui.openPopup(g, EventClientsView, min(60, maxX-10), len(clients)+1)
+	if err != nil {
+		return err
+	}
+
+	onQuit := func(g *gocui.Gui, v *gocui.View) error {
+		return ui.closePopup(g, EventClientsView)
+	}
+
+	view := []string{popup.Name()}
+	(&bindings{
+		{'q', "", "", view, func(*UI) ActionFn { return onQuit }},
+	}).Apply(ui, g)
+
+	popup.Title = "SSE clients"
+	return ui.renderEventClients(g)
+}
+
+func (ui *UI) renderEventClients(g *gocui.Gui) error {
+	v, err := g.View(EventClientsView)
+	if err != nil {
+		return err
+	}
+
+	v.Clear()
+	clients := ui.Brokers.Events.Clients()
+	if len(clients) == 0 {
+		fmt.Fprintln(v, "No SSE clients connected")
+	}
+	for _, c := range clients {
+		fmt.Fprintln(v, c)
+	}
+	return nil
+}
@@ -1,11 +1,12 @@
 package ui
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +38,18 @@ const (
 	BindingsView = "bindings"
 	// FileDialogView widget displays the popup to choose the response body file
 	FileDialogView = "file-dialog"
+	// ReplyView widget displays the upstream response of a replayed request
+	ReplyView = "reply"
+	// EventClientsView widget displays the connected SSE clients
+	EventClientsView = "sse-clients"
+	// FramesView widget displays the WebSocket frames
+	FramesView = "ws-frames"
+	// FrameInputView widget takes the WebSocket frames to send
+	FrameInputView = "ws-input"
+	// ConflictView widget asks what to do when the config file was modified by someone else
+	ConflictView = "conflict"
+	// ReloadView widget offers to apply the new version of the current response, once reloaded
+	ReloadView = "reload"
 )
 
 var cicleable = []string{
@@ -54,6 +67,13 @@ func max(a, b int) int {
 	return b
 }
 
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 // Cursors stores the cursor position for a specific view
 // this is used to restore mouse position when click is detected.
 type Cursors map[string]struct{ x, y int }
@@ -83,44 +103,94 @@ func (c Cursors) Set(view string, x, y int) {
 
 // UI represent the state of the ui.
 type UI struct {
-	resp                *httplab.Response
-	responses           *httplab.ResponsesList
-	infoTimer           *time.Timer
-	viewIndex           int
-	currentPopup        string
+	resp          *httplab.Response
+	responses     *httplab.ResponsesList
+	responsesLock sync.RWMutex
+	infoTimer     *time.Timer
+	viewIndex     int
+	currentPopup  string
+	// configPaths are the config files loaded, configPath the last one, where new responses are saved.
+	configPaths         []string
 	configPath          string
 	hideResponseBuilder bool
 	cursors             Cursors
 
 	reqLock        sync.Mutex
-	requests       [][]byte
+	requests       []*httplab.Request
 	currentRequest int
+	replayTarget   string
 
 	AutoUpdate bool
 	hasChanged bool
+
+	// Upstream is the URL requests are forwarded to, if any.
+	Upstream string
+	// breaking is set (atomically) while upstream responses are held to be edited.
+	breaking int32
+	holdLock sync.Mutex
+	held     *heldResponse
+
+	// frames is the WebSocket log, only touched by the gocui main loop.
+	frames []string
+
+	// Brokers push the events and frames sent from the UI to the SSE and WebSocket clients,
+	// the server has to serve through them.
+	Brokers *httplab.Brokers
+
+	// History, when set, persists the captured requests.
+	History *httplab.History
+	// TruncateHistory makes resetting the requests truncate the History as well.
+	TruncateHistory bool
 }
 
 // New returns a new UI with default values specified on the Response.
-func New(resp *httplab.Response, configPath string) *UI {
-	return &UI{
-		resp:       resp,
-		responses:  httplab.NewResponsesList(),
-		configPath: configPath,
-		cursors:    NewCursors(),
-	}
+// The saved responses are loaded from configPaths, each file overlaying the previous ones, see ResponsesList.Load.
+func New(resp *httplab.Response, configPaths ...string) *UI {
+	ui := &UI{
+		resp:        resp,
+		responses:   httplab.NewResponsesList(),
+		configPaths: configPaths,
+		cursors:     NewCursors(),
+		Brokers:     httplab.NewBrokers(),
+	}
+	if len(configPaths) > 0 {
+		ui.configPath = configPaths[len(configPaths)-1]
+	}
+	return ui
 }
 
-// Init initializes the UI.
-func (ui *UI) Init(g *gocui.Gui) (chan<- error, error) {
+// Init initializes the UI, the config file is watched until ctx is done.
+func (ui *UI) Init(ctx context.Context, g *gocui.Gui) (chan<- error, error) {
 	g.Cursor = true
 	g.Highlight = true
 	g.SelFgColor = gocui.ColorGreen
 	g.Mouse = true
 
+	for _, path := range ui.configPaths {
+		if _, err := os.Stat(path); err == nil {
+			if err := ui.loadResponses(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if ui.History != nil {
+		reqs, err := ui.History.Load()
+		if err != nil {
+			return nil, err
+		}
+		ui.requests = reqs
+		ui.currentRequest = max(len(reqs)-1, 0)
+	}
+
 	g.SetManager(ui)
 	if err := Bindings.Apply(ui, g); err != nil {
 		return nil, err
 	}
+	ui.watchEventClients(g)
+	ui.watchSockets(g)
+	go ui.watchConfig(ctx, g, configPollInterval)
 
 	errCh := make(chan error)
 	go func() {
@@ -162,22 +232,54 @@ func (ui *UI) Init(g *gocui.Gui) (chan<- error, error) {
 }
 
 // AddRequest adds a new request to the UI.
-func (ui *UI) AddRequest(g *gocui.Gui, req *http.Request) error {
+func (ui *UI) AddRequest(g *gocui.Gui, req *httplab.Request) error {
 	ui.reqLock.Lock()
 	defer ui.reqLock.Unlock()
 
-	ui.Info(g, "New Request from "+req.Host)
-	buf, err := httplab.DumpRequest(req)
-	if err != nil {
-		return err
-	}
+	ui.Info(g, "New Request from %s", req.Host)
 
 	if ui.currentRequest == len(ui.requests)-1 {
 		ui.currentRequest++
 	}
 
-	ui.requests = append(ui.requests, buf)
-	return ui.updateRequest(g)
+	ui.requests = append(ui.requests, req)
+	if err := ui.updateRequest(g); err != nil {
+		return err
+	}
+
+	if ui.History != nil {
+		return ui.History.Append(req)
+	}
+	return nil
+}
+
+// SetReply records the response sent to req, a request previously added with AddRequest.
+func (ui *UI) SetReply(g *gocui.Gui, req *httplab.Request, reply *httplab.Reply) error {
+	ui.reqLock.Lock()
+	defer ui.reqLock.Unlock()
+
+	req.Reply = reply
+	if showReply(req) {
+		g.Update(func(g *gocui.Gui) error {
+			ui.reqLock.Lock()
+			defer ui.reqLock.Unlock()
+			if len(ui.requests) > 0 && ui.requests[ui.currentRequest] == req {
+				return ui.updateRequest(g)
+			}
+			return nil
+		})
+	}
+
+	if ui.History != nil {
+		return ui.History.AppendReply(req)
+	}
+	return nil
+}
+
+// showReply tells whether the reply is displayed along with the request,
+// which is the case when it doesn't come from the response builder.
+func showReply(req *httplab.Request) bool {
+	return req.Reply != nil && (req.Upstream != "" || req.ReplayedTo != "")
 }
 
 func (ui *UI) updateRequest(g *gocui.Gui) error {
@@ -188,16 +290,53 @@ func (ui *UI) updateRequest(g *gocui.Gui) error {
 		return err
 	}
 
+	buf, err := req.Dump()
+	if err != nil {
+		return err
+	}
+
+	if showReply(req) {
+		reply, err := req.Reply.Dump()
+		if err != nil {
+			return err
+		}
+		buf = append(append(bytes.TrimRight(buf, "\n"), "\n\n"...), reply...)
+	}
+
 	view.Title = fmt.Sprintf("Request (%d/%d)", ui.currentRequest+1, len(ui.requests))
-	return ui.Display(g, RequestView, req)
+	if req.Upstream != "" {
+		view.Title += " - Upstream: " + req.Upstream
+	}
+	if req.ReplayedTo != "" {
+		view.Title += " - Replayed to " + req.ReplayedTo
+	}
+	if req.Route != "" {
+		view.Title += " - Route: " + req.Route
+	}
+	if req.Sequence != "" {
+		view.Title += " " + ui.sequenceLabel(req.Sequence)
+	}
+	return ui.Display(g, RequestView, buf)
 }
 
-func (ui *UI) resetRequests(g *gocui.Gui) error {
+func (ui *UI) clearRequests() error {
 	ui.reqLock.Lock()
 	defer ui.reqLock.Unlock()
 	ui.requests = nil
 	ui.currentRequest = 0
 
+	if ui.History != nil && ui.TruncateHistory {
+		return ui.History.Truncate()
+	}
+	return nil
+}
+
+func (ui *UI) resetRequests(g *gocui.Gui) error {
+	if err := ui.clearRequests(); err != nil {
+		ui.Info(g, "%v", err)
+		return nil
+	}
+
 	v, err := g.View(RequestView)
 	if err != nil {
 		return err
@@ -209,6 +348,31 @@ func (ui *UI) resetRequests(g *gocui.Gui) error {
 	return nil
 }
 
+// Requests returns a copy of the captured requests.
+func (ui *UI) Requests() []*httplab.Request {
+	ui.reqLock.Lock()
+	defer ui.reqLock.Unlock()
+	return copyRequests(ui.requests)
+}
+
+func copyRequests(reqs []*httplab.Request) []*httplab.Request {
+	cp := make([]*httplab.Request, len(reqs))
+	for i, req := range reqs {
+		r := *req
+		cp[i] = &r
+	}
+	return cp
+}
+
+// ResetRequests clears the request history.
+func (ui *UI) ResetRequests(g *gocui.Gui) error {
+	if err := ui.clearRequests(); err != nil {
+		return err
+	}
+	g.Update(ui.resetRequests)
+	return nil
+}
+
 // Layout sets the layout
 func (ui *UI) Layout(g *gocui.Gui) error {
 	maxX, maxY := g.Size()
@@ -228,6 +392,12 @@ func (ui *UI) Layout(g *gocui.Gui) error {
 		v.Title = "Request"
 		v.Editable = true
 		v.Editor = newEditor(ui, g, &motionEditor{})
+
+		ui.reqLock.Lock()
+		if len(ui.requests) > 0 {
+			ui.updateRequest(g)
+		}
+		ui.reqLock.Unlock()
 	}
 
 	if err := ui.setResponseView(g, splitX.Current(), 0, maxX-1, splitY.Current()); err != nil {
@@ -277,10 +447,10 @@ func (ui *UI) setResponseView(g *gocui.Gui, x0, y0, x1, y1 int) error {
 			return err
 		}
 
-		v.Title = "Delay (ms) "
+		v.Title = "Delay"
 		v.Editable = true
-		v.Editor = newEditor(ui, g, &numberEditor{9})
-		fmt.Fprintf(v, "%d", ui.resp.Delay/time.Millisecond)
+		v.Editor = newEditor(ui, g, &delayEditor{64})
+		fmt.Fprint(v, ui.resp.Delay)
 	}
 
 	if v, err := g.SetView(HeaderView, x0, split.Current(), x1, split.Next()); err != nil {
@@ -290,11 +460,7 @@ func (ui *UI) setResponseView(g *gocui.Gui, x0, y0, x1, y1 int) error {
 		v.Editable = true
 		v.Editor = newEditor(ui, g, nil)
 		v.Title = "Headers"
-		var headers []string
-		for key := range ui.resp.Headers {
-			headers = append(headers, key+": "+ui.resp.Headers.Get(key))
-		}
-		fmt.Fprint(v, strings.Join(headers, "\n"))
+		fmt.Fprint(v, strings.TrimSuffix(httplab.FormatHeaders(ui.resp.Headers), "\n"))
 	}
 
 	if v, err := g.SetView(BodyView, x0, split.Current(), x1, y1); err != nil {
@@ -354,6 +520,89 @@ func (ui *UI) Response() *httplab.Response {
 	return ui.resp
 }
 
+// SetResponse replaces the current response and displays it on the response builder.
+func (ui *UI) SetResponse(g *gocui.Gui, resp *httplab.Response) {
+	g.Update(func(g *gocui.Gui) error {
+		ui.resp = resp
+		ui.restoreResponse(g, resp)
+		return nil
+	})
+}
+
+// Responses returns a copy of the saved responses.
+func (ui *UI) Responses() map[string]*httplab.Response {
+	ui.responsesLock.RLock()
+	defer ui.responsesLock.RUnlock()
+
+	list := make(map[string]*httplab.Response, len(ui.responses.List))
+	for key, resp := range ui.responses.List {
+		list[key] = resp
+	}
+	return list
+}
+
+// SaveResponse saves resp as name into the config file, replacing any previous response with the same name
+// in the file it came from.
+// The changes made to the config file by someone else meanwhile are merged.
+func (ui *UI) SaveResponse(name string, resp *httplab.Response) error {
+	ui.responsesLock.Lock()
+	defer ui.responsesLock.Unlock()
+
+	ui.responses.Add(name, resp)
+	_, err := ui.responses.SaveMerging(ui.configPath)
+	return err
+}
+
+// DeleteResponse removes the response name from the config file, it returns false if it didn't exist.
+func (ui *UI) DeleteResponse(name string) (bool, error) {
+	ui.responsesLock.Lock()
+	defer ui.responsesLock.Unlock()
+
+	if !ui.responses.Del(name) {
+		return false, nil
+	}
+	_, err := ui.responses.SaveMerging(ui.configPath)
+	return true, err
+}
+
+// Match returns the route matching req and the response it points to.
+// When no route matches, the route is nil and the current Response is returned.
+func (ui *UI) Match(req *http.Request) (*httplab.Route, *httplab.Response) {
+	ui.responsesLock.RLock()
+	defer ui.responsesLock.RUnlock()
+
+	if route, resp := ui.responses.Match(req); route != nil {
+		return route, resp
+	}
+	return nil, ui.Response()
+}
+
+// sequenceLabel describes the current position of the sequence name.
+func (ui *UI) sequenceLabel(name string) string {
+	ui.responsesLock.RLock()
+	defer ui.responsesLock.RUnlock()
+
+	if seq, ok := ui.responses.Sequences[name]; ok {
+		return fmt.Sprintf("(%s %s)", name, seq)
+	}
+	return "(" + name + ")"
+}
+
+func (ui *UI) resetSequences(g *gocui.Gui) error {
+	ui.responsesLock.RLock()
+	defer ui.responsesLock.RUnlock()
+
+	ui.responses.ResetSequences()
+	ui.Info(g, "Sequences reset")
+	return nil
+}
+
+func (ui *UI) loadResponses() error {
+	ui.responsesLock.Lock()
+	defer ui.responsesLock.Unlock()
+	return ui.responses.Load(ui.configPaths...)
+}
+
 func (ui *UI) nextView(g *gocui.Gui) error {
 	if ui.hideResponseBuilder {
 		return nil
@@ -412,27 +661,33 @@ func (ui *UI) currentResponse(g *gocui.Gui) (*httplab.Response, error) {
 		return nil, err
 	}
 
+	// keeps telling which saved response it comes from, and how its values were written there
+	resp.Name = ui.resp.Name
+	resp.KeepPlaceholders(ui.resp)
 	resp.Body = ui.resp.Body
-	if ui.Response().Body.Mode == httplab.BodyInput {
+	resp.Throttle = ui.resp.Throttle
+	resp.Fault = ui.resp.Fault
+	resp.WebSocket = ui.resp.WebSocket
+	resp.Trailers = ui.resp.Trailers
+	resp.Push = ui.resp.Push
+	if mode := ui.Response().Body.Mode; mode == httplab.BodyInput || mode == httplab.BodyTemplate || mode == httplab.BodySSE {
 		resp.Body.Input = []byte(getViewBuffer(g, BodyView))
 	}
 
-	delay := getViewBuffer(g, DelayView)
-	delay = strings.Trim(delay, " \n")
-	intDelay, err := strconv.Atoi(delay)
+	resp.Delay, err = httplab.ParseDelay(getViewBuffer(g, DelayView))
 	if err != nil {
-		return nil, fmt.Errorf("Can't parse '%s' as number", delay)
+		return nil, err
 	}
-	resp.Delay = time.Duration(intDelay) * time.Millisecond
 
 	return resp, nil
 }
 
 func (ui *UI) updateResponse(g *gocui.Gui) error {
+	// invalid input is reported and kept on the views until it's fixed
 	resp, err := ui.currentResponse(g)
 	if err != nil {
-		ui.Info(g, err.Error())
-		return err
+		ui.Info(g, "%v", err)
+		return nil
 	}
 
 	ui.resp = resp
@@ -442,6 +697,10 @@ func (ui *UI) updateResponse(g *gocui.Gui) error {
 
 func (ui *UI) restoreResponse(g *gocui.Gui, r *httplab.Response) {
 	ui.resp = r
+	if ui.hideResponseBuilder {
+		ui.Info(g, "Response loaded!")
+		return
+	}
 
 	var v *gocui.View
 	v, _ = g.View(StatusView)
@@ -450,13 +709,11 @@ func (ui *UI) restoreResponse(g *gocui.Gui, r *httplab.Response) {
 
 	v, _ = g.View(DelayView)
 	v.Clear()
-	fmt.Fprintf(v, "%d", r.Delay)
+	fmt.Fprint(v, r.Delay)
 
 	v, _ = g.View(HeaderView)
 	v.Clear()
-	for key := range r.Headers {
-		fmt.Fprintf(v, "%s: %s", key, r.Headers.Get(key))
-	}
+	fmt.Fprint(v, httplab.FormatHeaders(r.Headers))
 
 	ui.renderBody(g)
 
@@ -507,6 +764,10 @@ func (ui *UI) closePopup(g *gocui.Gui, viewname string) error {
 
 	g.DeleteView(viewname)
 	g.DeleteKeybindings(viewname)
+	if viewname == FramesView {
+		g.DeleteView(FrameInputView)
+		g.DeleteKeybindings(FrameInputView)
+	}
 	g.Cursor = true
 	ui.currentPopup = ""
 	return ui.setView(g, cicleable[ui.viewIndex])
@@ -548,7 +809,7 @@ func (ui *UI) toggleResponsesLoader(g *gocui.Gui) error {
 		return ui.closePopup(g, ResponsesView)
 	}
 
-	if err := ui.responses.Load(ui.configPath); err != nil {
+	if err := ui.loadResponses(); err != nil {
 		return err
 	}
 
@@ -556,7 +817,21 @@ func (ui *UI) toggleResponsesLoader(g *gocui.Gui) error {
 		return errors.New("No responses has been saved")
 	}
 
-	popup, err := ui.openPopup(g, ResponsesView, 30, ui.responses.Len()+1)
+	// every response shows the config file it comes from
+	var lines []string
+	width := 0
+	for _, key := range ui.responses.Keys() {
+		width = max(width, len(key))
+	}
+	for _, key := range ui.responses.Keys() {
+		lines = append(lines, fmt.Sprintf("%-*s > %d  %s", width, key, ui.responses.Get(key).Status, ui.responses.Origin(key)))
+	}
+	width = 28
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+
+	popup, err := ui.openPopup(g, ResponsesView, width+2, ui.responses.Len()+1)
 	if err != nil {
 		return err
 	}
@@ -575,10 +850,13 @@ func (ui *UI) toggleResponsesLoader(g *gocui.Gui) error {
 	}
 
 	onDelete := func(g *gocui.Gui, v *gocui.View) error {
+		ui.responsesLock.Lock()
 		key := ui.responses.Keys()[ui.responses.Index()]
 		ui.responses.Del(key)
-		if err := ui.responses.Save(ui.configPath); err != nil {
-			return err
+		err := ui.saveResponses(g)
+		ui.responsesLock.Unlock()
+		if err != nil {
+			ui.Info(g, "%v", err)
 		}
 
 		if err := ui.closePopup(g, ResponsesView); err != nil {
@@ -610,8 +888,8 @@ func (ui *UI) toggleResponsesLoader(g *gocui.Gui) error {
 		{'q', "", "", view, func(*UI) ActionFn { return onQuit }},
 	}).Apply(ui, g)
 
-	for _, key := range ui.responses.Keys() {
-		fmt.Fprintf(popup, "%s > %d\n", key, ui.responses.Get(key).Status)
+	for _, line := range lines {
+		fmt.Fprintln(popup, line)
 	}
 
 	popup.Title = "Responses"
@@ -641,7 +919,7 @@ func (ui *UI) openSavePopup(g *gocui.Gui, title string, fn func(*gocui.Gui, stri
 	onEnter := func(g *gocui.Gui, v *gocui.View) error {
 		value := strings.Trim(v.Buffer(), " \n")
 		if err := fn(g, value); err != nil {
-			ui.Info(g, err.Error())
+			ui.Info(g, "%v", err)
 		}
 		return ui.closePopup(g, SaveView)
 	}
@@ -666,8 +944,11 @@ func (ui *UI) saveResponseAs(g *gocui.Gui, name string) error {
 		return err
 	}
 
+	ui.responsesLock.Lock()
+	defer ui.responsesLock.Unlock()
+
 	ui.responses.Add(name, resp)
-	if err := ui.responses.Save(ui.configPath); err != nil {
+	if err := ui.saveResponses(g); err != nil {
 		return err
 	}
 
@@ -691,22 +972,159 @@ func (ui *UI) saveRequestAs(g *gocui.Gui, name string) error {
 	if len(ui.requests) == 0 {
 		return nil
 	}
-	req := ui.requests[ui.currentRequest]
 
-	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err := exportRequests(name, ui.requests[ui.currentRequest:ui.currentRequest+1]); err != nil {
+		return err
+	}
+
+	ui.Info(g, "Request saved as '%s'", name)
+	return nil
+}
+
+func (ui *UI) exportRequestsPopup(g *gocui.Gui) error {
+	if len(ui.requests) == 0 {
+		ui.Info(g, "No Requests to export")
+		return nil
+	}
+
+	return ui.openSavePopup(g, "Export Requests as (.har, .curl, .http)...", ui.exportRequestsAs)
+}
+
+func (ui *UI) exportRequestsAs(g *gocui.Gui, name string) error {
+	ui.reqLock.Lock()
+	defer ui.reqLock.Unlock()
+	if len(ui.requests) == 0 {
+		return nil
+	}
+
+	if err := exportRequests(name, ui.requests); err != nil {
+		return err
+	}
+
+	ui.Info(g, "%d Requests exported as '%s'", len(ui.requests), name)
+	return nil
+}
+
+func (ui *UI) replayRequestPopup(g *gocui.Gui) error {
+	if len(ui.requests) == 0 {
+		ui.Info(g, "No Requests to replay")
+		return nil
+	}
+
+	if err := ui.openSavePopup(g, "Replay Request to (URL; Header: value; ...)", ui.replayRequest); err != nil {
+		return err
+	}
+
+	popup, err := g.View(SaveView)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	fmt.Fprint(popup, ui.replayTarget)
+	return popup.SetCursor(len(ui.replayTarget), 0)
+}
+
+// replayRequest sends the current request to the target given as "URL; Header: value; ...".
+// The replay is added to the requests, and the upstream response displayed once received.
+func (ui *UI) replayRequest(g *gocui.Gui, input string) error {
+	parts := strings.Split(input, ";")
+	target := strings.TrimSpace(parts[0])
 
-	if _, err := file.Write(httplab.Decolorize(req)); err != nil {
+	var lines []string
+	for _, part := range parts[1:] {
+		if part = strings.TrimSpace(part); part != "" {
+			lines = append(lines, part)
+		}
+	}
+	overrides, err := httplab.ParseHeaderOverrides(lines)
+	if err != nil {
 		return err
 	}
 
-	ui.Info(g, "Request saved as '%s'", name)
+	ui.reqLock.Lock()
+	if len(ui.requests) == 0 {
+		ui.reqLock.Unlock()
+		return nil
+	}
+	req := ui.requests[ui.currentRequest]
+	ui.replayTarget = input
+	ui.reqLock.Unlock()
+
+	ui.Info(g, "Replaying Request to %s", target)
+	go func() {
+		sent, err := req.Replay(context.Background(), nil, target, overrides)
+		g.Update(func(g *gocui.Gui) error {
+			if err != nil {
+				ui.Info(g, "Replay failed: %v", err)
+				return nil
+			}
+
+			if err := ui.AddRequest(g, sent); err != nil {
+				return err
+			}
+			if err := ui.SetReply(g, sent, sent.Reply); err != nil {
+				ui.Info(g, "%v", err)
+			}
+			return ui.openReplyPopup(g, sent)
+		})
+	}()
 	return nil
 }
 
+func (ui *UI) openReplyPopup(g *gocui.Gui, req *httplab.Request) error {
+	if err := ui.closePopup(g, ui.currentPopup); err != nil {
+		return err
+	}
+
+	buf, err := req.Reply.Dump()
+	if err != nil {
+		return err
+	}
+
+	maxX, maxY := g.Size()
+	popup, err := ui.openPopup(g, ReplyView, maxX-10, min(strings.Count(string(buf), "\n")+2, maxY-6))
+	if err != nil {
+		return err
+	}
+
+	onUp := func(g *gocui.Gui, v *gocui.View) error {
+		ox, oy := v.Origin()
+		return v.SetOrigin(ox, max(oy-1, 0))
+	}
+
+	onDown := func(g *gocui.Gui, v *gocui.View) error {
+		ox, oy := v.Origin()
+		return v.SetOrigin(ox, oy+1)
+	}
+
+	onQuit := func(g *gocui.Gui, v *gocui.View) error {
+		return ui.closePopup(g, ReplyView)
+	}
+
+	view := []string{popup.Name()}
+	(&bindings{
+		{gocui.KeyArrowUp, "", "", view, func(*UI) ActionFn { return onUp }},
+		{gocui.KeyArrowDown, "", "", view, func(*UI) ActionFn { return onDown }},
+		{'q', "", "", view, func(*UI) ActionFn { return onQuit }},
+	}).Apply(ui, g)
+
+	popup.Title = fmt.Sprintf("Reply from %s (q to close)", req.ReplayedTo)
+	popup.Wrap = true
+	popup.Clear()
+	_, err = popup.Write(buf)
+	return err
+}
+
+// exportRequests writes reqs into the file name, in the format its extension stands for.
+func exportRequests(name string, reqs []*httplab.Request) error {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return httplab.Export(file, httplab.ExportFormatFor(name), reqs)
+}
+
 func (ui *UI) renderBody(g *gocui.Gui) error {
 	v, err := g.View(BodyView)
 	if err != nil {
@@ -715,12 +1133,20 @@ func (ui *UI) renderBody(g *gocui.Gui) error {
 
 	body := ui.resp.Body
 
-	v.Title = fmt.Sprintf("Body (%s)", body.Mode)
+	v.Title = ui.bodyTitle()
 	v.Clear()
 	v.Write(body.Info())
 	return nil
 }
 
+func (ui *UI) bodyTitle() string {
+	mode := ui.resp.Body.Mode
+	if mode == httplab.BodySSE {
+		return fmt.Sprintf("Body (%s, %d clients, Ctrl+n sends)", mode, len(ui.Brokers.Events.Clients()))
+	}
+	return fmt.Sprintf("Body (%s)", mode)
+}
+
 func (ui *UI) openBodyFilePopup(g *gocui.Gui) error {
 	if err := ui.closePopup(g, ui.currentPopup); err != nil {
 		return err
@@ -758,6 +1184,8 @@ func (ui *UI) nextBodyMode(g *gocui.Gui) error {
 	modes := []httplab.BodyMode{
 		httplab.BodyInput,
 		httplab.BodyFile,
+		httplab.BodyTemplate,
+		httplab.BodySSE,
 	}
 	body := &ui.resp.Body
 	body.Mode = body.Mode%httplab.BodyMode(len(modes)) + 1
@@ -6,9 +6,9 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
-	"strings"
 	"testing"
 
+	"github.com/gchaincl/httplab"
 	"github.com/jroimartin/gocui"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,21 +24,24 @@ func newTestUI(t *testing.T) (*gocui.Gui, *UI) {
 	return g, ui
 }
 
+func newRequest(t *testing.T, req *http.Request) *httplab.Request {
+	r, err := httplab.NewRequest(req)
+	require.NoError(t, err)
+	return r
+}
+
 func TestUIAddRequestSavesInOrder(t *testing.T) {
 	g, ui := newTestUI(t)
 	defer g.Close()
 
 	for i := 0; i < 10; i++ {
 		req, _ := http.NewRequest("GET", fmt.Sprintf("/%d", i), &bytes.Buffer{})
-		require.NoError(t, ui.AddRequest(g, req))
+		require.NoError(t, ui.AddRequest(g, newRequest(t, req)))
 	}
 
 	assert.Len(t, ui.requests, 10)
 	for i := 0; i < 10; i++ {
-		req := ui.requests[i]
-		split := strings.Split(string(req), " ")
-		path := split[1]
-		assert.Equal(t, fmt.Sprintf("/%d", i), path)
+		assert.Equal(t, fmt.Sprintf("/%d", i), ui.requests[i].URL)
 	}
 
 	assert.Equal(t, 9, ui.currentRequest)
@@ -50,7 +53,7 @@ func TestUIScrollRequests(t *testing.T) {
 
 	for i := 0; i < 10; i++ {
 		req, _ := http.NewRequest("GET", fmt.Sprintf("/%d", i), &bytes.Buffer{})
-		require.NoError(t, ui.AddRequest(g, req))
+		require.NoError(t, ui.AddRequest(g, newRequest(t, req)))
 	}
 
 	cur := ui.currentRequest
@@ -65,7 +68,7 @@ func TestUIScrollRequests(t *testing.T) {
 		ui.prevRequest(g)
 		cur := ui.currentRequest
 
-		ui.AddRequest(g, req)
+		ui.AddRequest(g, newRequest(t, req))
 		assert.Equal(t, cur, ui.currentRequest)
 	})
 }
@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gchaincl/httplab"
+	"github.com/jroimartin/gocui"
+)
+
+// maxFrames is the amount of WebSocket log lines kept to be displayed.
+const maxFrames = 1000
+
+// watchSockets logs the WebSocket frames exchanged with the clients, along with the clients
+// connecting and disconnecting, which are reported on the info bar too.
+func (ui *UI) watchSockets(g *gocui.Gui) {
+	ui.Brokers.Sockets.Notify = func(c httplab.SocketClient, connected bool) {
+		line := fmt.Sprintf("%s #%d disconnected", time.Now().Format("15:04:05"), c.ID)
+		if connected {
+			line = fmt.Sprintf("%s #%d connected from %s to %s", c.ConnectedAt.Format("15:04:05"), c.ID, c.RemoteAddr, c.Path)
+			if c.Subprotocol != "" {
+				line += " (" + c.Subprotocol + ")"
+			}
+			ui.Info(g, "WebSocket client #%d connected from %s, Ctrl+g shows the frames", c.ID, c.RemoteAddr)
+		} else {
+			ui.Info(g, "WebSocket client #%d disconnected", c.ID)
+		}
+		ui.logFrame(g, line)
+	}
+
+	ui.Brokers.Sockets.OnFrame = func(f httplab.Frame) {
+		ui.logFrame(g, f.String())
+	}
+}
+
+// logFrame appends line to the WebSocket log.
+func (ui *UI) logFrame(g *gocui.Gui, line string) {
+	g.Update(func(g *gocui.Gui) error {
+		ui.frames = append(ui.frames, line)
+		if len(ui.frames) > maxFrames {
+			ui.frames = ui.frames[len(ui.frames)-maxFrames:]
+		}
+		return ui.renderFrames(g)
+	})
+}
+
+// toggleFrames shows the WebSocket frames, with an input to send frames to every client below them.
+func (ui *UI) toggleFrames(g *gocui.Gui) error {
+	if ui.currentPopup == FramesView {
+		return ui.closePopup(g, FramesView)
+	}
+
+	if err := ui.closePopup(g, ui.currentPopup); err != nil {
+		return err
+	}
+
+	maxX, maxY := g.Size()
+	popup, err := ui.openPopup(g, FramesView, maxX-10, maxY-10)
+	if err != nil {
+		return err
+	}
+	popup.Autoscroll = true
+
+	x0, _, x1, y1, err := g.ViewPosition(FramesView)
+	if err != nil {
+		return err
+	}
+	input, err := g.SetView(FrameInputView, x0, y1+1, x1, y1+3)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	input.Title = "Send: text, binary: <hex>, ping, pong or close: <code> <reason>"
+	input.Editable = true
+
+	if _, err := g.SetCurrentView(FrameInputView); err != nil {
+		return err
+	}
+	g.Cursor = true
+
+	onUp := func(g *gocui.Gui, v *gocui.View) error {
+		popup.Autoscroll = false
+		ox, oy := popup.Origin()
+		return popup.SetOrigin(ox, max(oy-1, 0))
+	}
+
+	onDown := func(g *gocui.Gui, v *gocui.View) error {
+		ox, oy := popup.Origin()
+		_, height := popup.Size()
+		if oy+height >= len(popup.BufferLines())-1 {
+			popup.Autoscroll = true
+			return nil
+		}
+		return popup.SetOrigin(ox, oy+1)
+	}
+
+	onEnter := func(g *gocui.Gui, v *gocui.View) error {
+		return ui.sendFrame(g, v)
+	}
+
+	view := []string{FrameInputView}
+	(&bindings{
+		{gocui.KeyArrowUp, "", "", view, func(*UI) ActionFn { return onUp }},
+		{gocui.KeyArrowDown, "", "", view, func(*UI) ActionFn { return onDown }},
+		{gocui.KeyEnter, "", "", view, func(*UI) ActionFn { return onEnter }},
+	}).Apply(ui, g)
+
+	return ui.renderFrames(g)
+}
+
+func (ui *UI) renderFrames(g *gocui.Gui) error {
+	v, err := g.View(FramesView)
+	if err != nil {
+		return nil
+	}
+
+	v.Title = fmt.Sprintf("WebSocket frames (%d clients, Ctrl+g closes)", len(ui.Brokers.Sockets.Clients()))
+	v.Clear()
+	if len(ui.frames) == 0 {
+		fmt.Fprintln(v, "No frames yet")
+	}
+	for _, line := range ui.frames {
+		fmt.Fprintln(v, line)
+	}
+	return nil
+}
+
+// sendFrame sends the frame typed on v to every WebSocket client.
+func (ui *UI) sendFrame(g *gocui.Gui, v *gocui.View) error {
+	input := strings.TrimSpace(v.Buffer())
+	if input == "" {
+		return nil
+	}
+
+	f, err := httplab.ParseFrame(input)
+	if err != nil {
+		ui.Info(g, "%v", err)
+		return nil
+	}
+
+	n, err := ui.Brokers.Sockets.Broadcast(f)
+	if err != nil {
+		ui.Info(g, "Frame sent to %d WebSocket clients: %v", n, err)
+	} else {
+		ui.Info(g, "Frame sent to %d WebSocket clients", n)
+	}
+
+	v.Clear()
+	v.SetOrigin(0, 0)
+	return v.SetCursor(0, 0)
+}
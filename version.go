@@ -0,0 +1,4 @@
+package httplab
+
+// Version is the current version
+const Version = "v0.5.0-dev"
@@ -0,0 +1,435 @@
+package httplab
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// controlTimeout is the time given to write control frames.
+const controlTimeout = time.Second
+
+// FrameType is the kind of a WebSocket frame.
+type FrameType string
+
+// FrameType values
+const (
+	FrameText   FrameType = "text"
+	FrameBinary FrameType = "binary"
+	FramePing   FrameType = "ping"
+	FramePong   FrameType = "pong"
+	FrameClose  FrameType = "close"
+)
+
+// Frame is a WebSocket frame received from or sent to a client.
+type Frame struct {
+	Type FrameType
+	Data []byte
+	// CloseCode is the status code of close frames.
+	CloseCode int
+
+	// Client is the ID of the client the frame comes from or goes to.
+	Client   int
+	Incoming bool
+	Time     time.Time
+}
+
+// ParseFrame parses a frame to be sent, written as "type: payload":
+//
+//	hello                      a text frame
+//	text: ping: not a ping     a text frame, which would be taken as another type otherwise
+//	binary: 01 02 ff           a binary frame, written in hex
+//	ping: payload
+//	pong
+//	close: 1000 bye            a close frame, with its code and reason
+func ParseFrame(s string) (Frame, error) {
+	s = strings.TrimRight(s, "\r\n")
+	kind, payload := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		kind, payload = s[:i], strings.TrimPrefix(s[i+1:], " ")
+	}
+
+	switch t := FrameType(kind); t {
+	case FrameText, FramePing, FramePong:
+		return Frame{Type: t, Data: []byte(payload)}, nil
+	case FrameBinary:
+		data, err := hex.DecodeString(strings.Join(strings.Fields(payload), ""))
+		if err != nil {
+			return Frame{}, fmt.Errorf("WebSocket: binary frames are written in hex: %v", err)
+		}
+		return Frame{Type: t, Data: data}, nil
+	case FrameClose:
+		f := Frame{Type: t, CloseCode: websocket.CloseNormalClosure}
+		fields := strings.SplitN(payload, " ", 2)
+		if fields[0] != "" {
+			code, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return Frame{}, fmt.Errorf("WebSocket: invalid close code '%s'", fields[0])
+			}
+			f.CloseCode = code
+		}
+		if len(fields) == 2 {
+			f.Data = []byte(fields[1])
+		}
+		return f, nil
+	}
+
+	return Frame{Type: FrameText, Data: []byte(s)}, nil
+}
+
+// String describes the frame in a single line, binary payloads are written in hex.
+func (f Frame) String() string {
+	dir := "->"
+	if f.Incoming {
+		dir = "<-"
+	}
+
+	var payload string
+	switch f.Type {
+	case FrameBinary:
+		payload = fmt.Sprintf("(%d bytes) % x", len(f.Data), f.Data)
+	case FrameClose:
+		payload = fmt.Sprintf("%d %s", f.CloseCode, f.Data)
+	default:
+		payload = strconv.Quote(string(f.Data))
+	}
+
+	return fmt.Sprintf("%s #%d %s %-6s %s", f.Time.Format("15:04:05"), f.Client, dir, f.Type, strings.TrimSpace(payload))
+}
+
+// SocketRule replies to the incoming text frames matching Regexp.
+type SocketRule struct {
+	Regexp string
+	// Reply is a frame as read by ParseFrame, $1 style references are expanded with the Regexp groups.
+	Reply string
+
+	regexp *regexp.Regexp
+}
+
+func (r *SocketRule) compile() error {
+	re, err := regexp.Compile(r.Regexp)
+	if err != nil {
+		return fmt.Errorf("WebSocket rule %s: %v", r.Regexp, err)
+	}
+	r.regexp = re
+	return nil
+}
+
+// reply returns the frame replying to data, false if the rule doesn't match.
+func (r *SocketRule) reply(data []byte) (Frame, bool, error) {
+	if r.regexp == nil {
+		if err := r.compile(); err != nil {
+			return Frame{}, false, err
+		}
+	}
+
+	match := r.regexp.FindSubmatchIndex(data)
+	if match == nil {
+		return Frame{}, false, nil
+	}
+
+	reply := r.regexp.Expand(nil, []byte(r.Reply), data, match)
+	f, err := ParseFrame(string(reply))
+	return f, true, err
+}
+
+// WebSocket accepts the WebSocket handshake on a response, instead of writing it.
+//
+// In the config:
+//
+//	"WebSocket": {"Echo": true, "Rules": [{"Regexp": "^subscribe (\\w+)$", "Reply": "subscribed to $1"}]}
+type WebSocket struct {
+	// Echo sends back the incoming text and binary frames no rule replies to.
+	Echo bool `json:",omitempty"`
+	// Rules reply to the incoming text frames, the first matching one is used.
+	Rules []*SocketRule `json:",omitempty"`
+	// Subprotocols are the subprotocols accepted, in order of preference.
+	Subprotocols []string `json:",omitempty"`
+}
+
+// UnmarshalJSON validates the rules.
+func (ws *WebSocket) UnmarshalJSON(data []byte) error {
+	type alias WebSocket
+	if err := json.Unmarshal(data, (*alias)(ws)); err != nil {
+		return err
+	}
+
+	for _, rule := range ws.Rules {
+		if err := rule.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// autoReply returns the frame replying to f, if any.
+func (ws *WebSocket) autoReply(f Frame) (*Frame, error) {
+	if f.Type == FrameText {
+		for _, rule := range ws.Rules {
+			reply, ok, err := rule.reply(f.Data)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return &reply, nil
+			}
+		}
+	}
+
+	if ws.Echo && (f.Type == FrameText || f.Type == FrameBinary) {
+		return &Frame{Type: f.Type, Data: f.Data}, nil
+	}
+	return nil, nil
+}
+
+// SocketClient is a client connected through WebSocket.
+type SocketClient struct {
+	ID          int
+	RemoteAddr  string
+	Path        string
+	Subprotocol string
+	ConnectedAt time.Time
+}
+
+// String describes the client in a single line.
+func (c SocketClient) String() string {
+	s := fmt.Sprintf("#%d %s %s", c.ID, c.RemoteAddr, c.Path)
+	if c.Subprotocol != "" {
+		s += " (" + c.Subprotocol + ")"
+	}
+	return fmt.Sprintf("%s, connected %s ago", s, time.Since(c.ConnectedAt).Truncate(time.Second))
+}
+
+type socketClient struct {
+	SocketClient
+	conn *websocket.Conn
+	// mu serializes the data frames written into conn.
+	mu sync.Mutex
+}
+
+// SocketBroker keeps track of the WebSocket clients, reporting the frames they exchange.
+type SocketBroker struct {
+	// Notify, when set, is called each time a client connects or disconnects.
+	Notify func(c SocketClient, connected bool)
+	// OnFrame, when set, is called for each frame received from or sent to a client.
+	OnFrame func(f Frame)
+
+	mu      sync.Mutex
+	clients []*socketClient
+	lastID  int
+	closed  bool
+}
+
+// Close closes the connections of the clients, and of the ones connecting afterwards.
+func (b *SocketBroker) Close() {
+	b.mu.Lock()
+	b.closed = true
+	clients := append([]*socketClient(nil), b.clients...)
+	b.mu.Unlock()
+
+	for _, c := range clients {
+		b.send(c, Frame{Type: FrameClose, CloseCode: websocket.CloseGoingAway})
+		c.conn.Close()
+	}
+}
+
+// isClosed reports whether Close was called.
+func (b *SocketBroker) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// Clients returns the connected clients, in the order they connected.
+func (b *SocketBroker) Clients() []SocketClient {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clients := make([]SocketClient, len(b.clients))
+	for i, c := range b.clients {
+		clients[i] = c.SocketClient
+	}
+	return clients
+}
+
+// Broadcast sends f to every connected client, it returns the amount of clients it was sent to.
+func (b *SocketBroker) Broadcast(f Frame) (int, error) {
+	b.mu.Lock()
+	clients := append([]*socketClient(nil), b.clients...)
+	b.mu.Unlock()
+
+	var lastErr error
+	n := 0
+	for _, c := range clients {
+		if err := b.send(c, f); err != nil {
+			lastErr = err
+			continue
+		}
+		n++
+	}
+	return n, lastErr
+}
+
+// Serve completes the WebSocket handshake, then reads frames until the connection is closed,
+// auto-replying to them as configured on r.WebSocket.
+func (b *SocketBroker) Serve(w http.ResponseWriter, req *http.Request, r *Response) error {
+	upgrader := websocket.Upgrader{
+		Subprotocols: r.WebSocket.Subprotocols,
+		CheckOrigin:  func(*http.Request) bool { return true },
+	}
+
+	hdr := http.Header{}
+	for key, values := range r.Headers {
+		if strings.HasPrefix(http.CanonicalHeaderKey(key), "Sec-Websocket-") {
+			continue
+		}
+		hdr[key] = values
+	}
+
+	conn, err := upgrader.Upgrade(w, req, hdr)
+	if err != nil {
+		return fmt.Errorf("WebSocket: %v", err)
+	}
+	defer conn.Close()
+
+	c := b.connect(req, conn)
+	if c == nil {
+		return nil
+	}
+	defer b.disconnect(c)
+
+	conn.SetPingHandler(func(data string) error {
+		b.received(c, Frame{Type: FramePing, Data: []byte(data)})
+		return b.send(c, Frame{Type: FramePong, Data: []byte(data)})
+	})
+	conn.SetPongHandler(func(data string) error {
+		b.received(c, Frame{Type: FramePong, Data: []byte(data)})
+		return nil
+	})
+	conn.SetCloseHandler(func(code int, text string) error {
+		b.received(c, Frame{Type: FrameClose, CloseCode: code, Data: []byte(text)})
+		return b.send(c, Frame{Type: FrameClose, CloseCode: code})
+	})
+
+	for {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			if _, ok := err.(*websocket.CloseError); ok || b.isClosed() {
+				return nil
+			}
+			return fmt.Errorf("WebSocket: %v", err)
+		}
+
+		f := Frame{Type: FrameText, Data: data}
+		if kind == websocket.BinaryMessage {
+			f.Type = FrameBinary
+		}
+		b.received(c, f)
+
+		reply, err := r.WebSocket.autoReply(f)
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			if err := b.send(c, *reply); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// send writes f into the connection of c.
+func (b *SocketBroker) send(c *socketClient, f Frame) error {
+	var err error
+	switch f.Type {
+	case FrameText, FrameBinary:
+		kind := websocket.TextMessage
+		if f.Type == FrameBinary {
+			kind = websocket.BinaryMessage
+		}
+		c.mu.Lock()
+		err = c.conn.WriteMessage(kind, f.Data)
+		c.mu.Unlock()
+	case FramePing, FramePong:
+		kind := websocket.PingMessage
+		if f.Type == FramePong {
+			kind = websocket.PongMessage
+		}
+		err = c.conn.WriteControl(kind, f.Data, time.Now().Add(controlTimeout))
+	case FrameClose:
+		msg := websocket.FormatCloseMessage(f.CloseCode, string(f.Data))
+		err = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(controlTimeout))
+	default:
+		err = fmt.Errorf("WebSocket: unknown frame type '%s'", f.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.Client = c.ID
+	f.Incoming = false
+	f.Time = time.Now()
+	if b.OnFrame != nil {
+		b.OnFrame(f)
+	}
+	return nil
+}
+
+func (b *SocketBroker) received(c *socketClient, f Frame) {
+	f.Client = c.ID
+	f.Incoming = true
+	f.Time = time.Now()
+	if b.OnFrame != nil {
+		b.OnFrame(f)
+	}
+}
+
+// connect adds the client of conn, it returns nil once b is closed.
+func (b *SocketBroker) connect(req *http.Request, conn *websocket.Conn) *socketClient {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.lastID++
+	c := &socketClient{
+		SocketClient: SocketClient{
+			ID:          b.lastID,
+			RemoteAddr:  req.RemoteAddr,
+			Path:        req.URL.Path,
+			Subprotocol: conn.Subprotocol(),
+			ConnectedAt: time.Now(),
+		},
+		conn: conn,
+	}
+	b.clients = append(b.clients, c)
+	b.mu.Unlock()
+
+	if b.Notify != nil {
+		b.Notify(c.SocketClient, true)
+	}
+	return c
+}
+
+func (b *SocketBroker) disconnect(c *socketClient) {
+	b.mu.Lock()
+	for i := range b.clients {
+		if b.clients[i] == c {
+			b.clients = append(b.clients[:i], b.clients[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if b.Notify != nil {
+		b.Notify(c.SocketClient, false)
+	}
+}
@@ -0,0 +1,200 @@
+package httplab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFrame(t *testing.T) {
+	for input, expected := range map[string]Frame{
+		"hello":                {Type: FrameText, Data: []byte("hello")},
+		"hello: world":         {Type: FrameText, Data: []byte("hello: world")},
+		"text: ping: not ping": {Type: FrameText, Data: []byte("ping: not ping")},
+		"binary: 01 02 ff":     {Type: FrameBinary, Data: []byte{1, 2, 0xff}},
+		"ping: abc":            {Type: FramePing, Data: []byte("abc")},
+		"pong":                 {Type: FramePong, Data: []byte("")},
+		"close":                {Type: FrameClose, CloseCode: 1000},
+		"close: 4001 go away":  {Type: FrameClose, CloseCode: 4001, Data: []byte("go away")},
+	} {
+		f, err := ParseFrame(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, f, input)
+	}
+
+	_, err := ParseFrame("binary: zz")
+	assert.Error(t, err)
+	_, err = ParseFrame("close: abc")
+	assert.Error(t, err)
+}
+
+func TestFrameString(t *testing.T) {
+	at := time.Date(2017, 1, 1, 10, 20, 30, 0, time.UTC)
+	assert.Equal(t, `10:20:30 #1 <- text   "hi\n"`, Frame{Type: FrameText, Data: []byte("hi\n"), Client: 1, Incoming: true, Time: at}.String())
+	assert.Equal(t, `10:20:30 #2 -> binary (3 bytes) 01 02 ff`, Frame{Type: FrameBinary, Data: []byte{1, 2, 0xff}, Client: 2, Time: at}.String())
+	assert.Equal(t, `10:20:30 #1 <- close  1001 bye`, Frame{Type: FrameClose, CloseCode: 1001, Data: []byte("bye"), Client: 1, Incoming: true, Time: at}.String())
+}
+
+func TestWebSocketJSON(t *testing.T) {
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"Status": 200,
+		"WebSocket": {"Echo": true, "Rules": [{"Regexp": "^sub (\\w+)$", "Reply": "subscribed to $1"}]}
+	}`), &resp))
+	require.NotNil(t, resp.WebSocket)
+	assert.True(t, resp.WebSocket.Echo)
+	require.Len(t, resp.WebSocket.Rules, 1)
+
+	data, err := json.Marshal(&resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"WebSocket":{"Echo":true,"Rules":[{"Regexp":"^sub (\\w+)$","Reply":"subscribed to $1"}]}`)
+
+	err = json.Unmarshal([]byte(`{"WebSocket": {"Rules": [{"Regexp": "("}]}}`), &resp)
+	assert.Error(t, err)
+}
+
+type frameLog struct {
+	mu     sync.Mutex
+	frames []Frame
+}
+
+func (l *frameLog) add(f Frame) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.frames = append(l.frames, f)
+}
+
+func (l *frameLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var lines []string
+	for _, f := range l.frames {
+		lines = append(lines, strings.SplitN(f.String(), " ", 2)[1])
+	}
+	return lines
+}
+
+func TestSocketBroker(t *testing.T) {
+	log := &frameLog{}
+	connections := make(chan bool, 2)
+	b := &SocketBroker{
+		OnFrame: log.add,
+		Notify: func(c SocketClient, connected bool) {
+			connections <- connected
+		},
+	}
+
+	resp := &Response{
+		Status:  200,
+		Headers: http.Header{"X-Server": []string{"HTTPLab"}},
+		WebSocket: &WebSocket{
+			Echo:         true,
+			Rules:        []*SocketRule{{Regexp: `^sub (\w+)$`, Reply: "subscribed to $1"}},
+			Subprotocols: []string{"chat"},
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b.Serve(w, req, resp)
+	}))
+	defer srv.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"chat"}}
+	conn, res, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "HTTPLab", res.Header.Get("X-Server"))
+	assert.Equal(t, "chat", conn.Subprotocol())
+	assert.True(t, <-connections)
+
+	clients := b.Clients()
+	require.Len(t, clients, 1)
+	assert.Equal(t, "/ws", clients[0].Path)
+	assert.Equal(t, "chat", clients[0].Subprotocol)
+
+	read := func() (int, string) {
+		kind, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		return kind, string(data)
+	}
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("sub prices")))
+	kind, data := read()
+	assert.Equal(t, websocket.TextMessage, kind)
+	assert.Equal(t, "subscribed to prices", data)
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte{0xca, 0xfe}))
+	kind, data = read()
+	assert.Equal(t, websocket.BinaryMessage, kind)
+	assert.Equal(t, "\xca\xfe", data)
+
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pong <- data
+		return nil
+	})
+	require.NoError(t, conn.WriteControl(websocket.PingMessage, []byte("are you there"), time.Now().Add(time.Second)))
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("after ping")))
+	_, data = read()
+	assert.Equal(t, "after ping", data)
+	assert.Equal(t, "are you there", <-pong)
+
+	n, err := b.Broadcast(Frame{Type: FrameText, Data: []byte("pushed")})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, data = read()
+	assert.Equal(t, "pushed", data)
+
+	require.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4001, "bye")))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, 4001), "%v", err)
+	assert.False(t, <-connections)
+	assert.Empty(t, b.Clients())
+
+	assert.Equal(t, []string{
+		`#1 <- text   "sub prices"`,
+		`#1 -> text   "subscribed to prices"`,
+		`#1 <- binary (2 bytes) ca fe`,
+		`#1 -> binary (2 bytes) ca fe`,
+		`#1 <- ping   "are you there"`,
+		`#1 -> pong   "are you there"`,
+		`#1 <- text   "after ping"`,
+		`#1 -> text   "after ping"`,
+		`#1 -> text   "pushed"`,
+		`#1 <- close  4001 bye`,
+		`#1 -> close  4001`,
+	}, log.get())
+}
+
+func TestResponseServeWebSocket(t *testing.T) {
+	resp := &Response{
+		Status:    200,
+		Body:      Body{Mode: BodyInput, Input: []byte("not a websocket")},
+		WebSocket: &WebSocket{Echo: true},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		resp.Serve(w, req)
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, 200, res.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("echo")))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "echo", string(data))
+}